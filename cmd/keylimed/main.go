@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
@@ -11,8 +12,10 @@ import (
 	"os"
 	"time"
 
+	kerrors "github.com/namvu9/keylime/src/errors"
 	"github.com/namvu9/keylime/src/queries"
 	"github.com/namvu9/keylime/src/store"
+	"github.com/namvu9/keylime/src/types"
 )
 
 func prettify(v interface{}) (string, error) {
@@ -27,19 +30,65 @@ func prettify(v interface{}) (string, error) {
 	return string(b), nil
 }
 
-func readConfig() (*store.Config, error) {
-	cfg := &store.Config{
-		BaseDir: "./testdata",
-		Host:    "localhost",
-		Port:    "1337",
+// errorResponse is sent to the client when a request is
+// rejected because of invalid fields or documents
+type errorResponse struct {
+	Error     string             `json:"error"`
+	Code      kerrors.Code       `json:"code"`
+	Fields    []types.FieldError `json:"fields,omitempty"`
+	Documents types.BatchError   `json:"documents,omitempty"`
+}
+
+// formatError returns the message sent to the client for
+// `err`. Validation and batch errors are encoded as JSON so
+// that clients can tell which fields were rejected.
+func formatError(err error) string {
+	var (
+		ve  types.ValidationError
+		be  types.BatchError
+		res = errorResponse{Code: kerrors.GetKind(err)}
+	)
+
+	switch {
+	case kerrors.As(err, &be):
+		res.Error = "Batch rejected"
+		res.Documents = be
+	case kerrors.As(err, &ve):
+		res.Error = "Validation failed"
+		res.Fields = ve.Fields()
+	default:
+		return fmt.Sprintf("Error: %s", err)
+	}
+
+	s, _ := prettify(res)
+	return s
+}
+
+// readConfig reads the configuration from the command-line
+// flags in `args`
+func readConfig(args []string) (*store.Config, error) {
+	cfg := &store.Config{}
+
+	fs := flag.NewFlagSet("keylimed", flag.ContinueOnError)
+	fs.StringVar(&cfg.BaseDir, "dir", "./testdata", "Directory that the data is stored in")
+	fs.StringVar(&cfg.Host, "host", "localhost", "Host to listen on")
+	fs.StringVar(&cfg.Port, "port", "1337", "Port to listen on")
+	fs.StringVar(&cfg.ImportDir, "import-dir", "./import", "Directory that LOAD reads files from. LOAD is disabled if empty")
+	fs.BoolVar(&cfg.AutoCreate, "auto-create", false, "Create collections that do not exist on the first write to them")
+	fs.DurationVar(&cfg.ReapInterval, "reap-interval", time.Minute, "Interval at which expired documents are deleted")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
 }
 
 func main() {
-	cfg, err := readConfig()
-	if err != nil {
+	cfg, err := readConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
 		fmt.Fprintf(os.Stderr, "Could not load config: %s", err)
 		os.Exit(1)
 	}
@@ -52,6 +101,8 @@ func main() {
 	s := store.New(cfg)
 	timeout := time.Minute
 
+	go s.StartReaper(context.Background(), cfg.ReapInterval)
+
 	log.Printf("Listening on %s\n", listener.Addr())
 
 	for {
@@ -65,6 +116,10 @@ func main() {
 				ctx, cancel := context.WithTimeout(context.Background(), timeout)
 				defer cancel()
 
+				// The client's address is the value of $client in
+				// computed fields
+				ctx = types.WithClient(ctx, c.RemoteAddr().String())
+
 				buf := make([]byte, 1000)
 				n, err := conn.Read(buf)
 				if err != nil {
@@ -79,7 +134,7 @@ func main() {
 				res, err := queries.Interpret(ctx, s, string(buf[:n]))
 				if err != nil {
 					log.Printf("Error: %s\n", err)
-					conn.Write([]byte(fmt.Sprintf("Error: %s", err)))
+					conn.Write([]byte(formatError(err)))
 				} else if res != nil {
 					s, _ := prettify(res)
 					conn.Write([]byte(s))
@@ -1,6 +1,7 @@
 package errors
 
 import (
+	"errors"
 	"fmt"
 )
 
@@ -11,6 +12,14 @@ const (
 	ENotFound Code = "NotFound"
 	EIO            = "IO Error"
 
+	// The request conflicts with the current state of the
+	// resource, e.g. a document with the key already exists
+	EConflict = "Conflict"
+
+	// A conditional write was rejected because the document
+	// changed since the version the client expected
+	EVersionMismatch = "VersionMismatch"
+
 	// The application received a request that it did not know
 	// how to handle
 	EBadRequest = "Bad request"
@@ -52,6 +61,11 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("%s:\n %s", e.Op, e.Err.Error())
 }
 
+// Unwrap returns the wrapped error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
 func (e Error) Is(target error) bool {
 	if other, ok := target.(*Error); ok {
 		return e.Code == other.Code
@@ -82,6 +96,49 @@ func NewKeyNotFoundError(op Op, key string) *Error {
 	}
 }
 
+// ErrCollectionNotFound and ErrCollectionExists are wrapped
+// by the errors that report a missing collection and an
+// existing one, respectively. Use Is to find them in the
+// chain of an error.
+var (
+	ErrCollectionNotFound = errors.New("CollectionNotFound")
+	ErrCollectionExists   = errors.New("CollectionExists")
+)
+
+func NewCollectionNotFoundError(op Op, name string) *Error {
+	return &Error{
+		Op:         op,
+		Code:       ENotFound,
+		Err:        fmt.Errorf("%w: %s", ErrCollectionNotFound, name),
+		Collection: name,
+	}
+}
+
+func NewCollectionExistsError(op Op, name string) *Error {
+	return &Error{
+		Op:         op,
+		Code:       EConflict,
+		Err:        fmt.Errorf("%w: %s", ErrCollectionExists, name),
+		Collection: name,
+	}
+}
+
+func NewKeyExistsError(op Op, key string) *Error {
+	return &Error{
+		Op:   op,
+		Code: EConflict,
+		Err:  fmt.Errorf("KeyExists: %s", key),
+	}
+}
+
+func NewVersionMismatchError(op Op, key, want, got string) *Error {
+	return &Error{
+		Op:   op,
+		Code: EVersionMismatch,
+		Err:  fmt.Errorf("VersionMismatch: %s has version %s, expected %s", key, got, want),
+	}
+}
+
 func Wrap(op Op, kind Code, err error) *Error {
 	return &Error{
 		Op:   op,
@@ -99,3 +156,15 @@ func WrapWith(op Op, kind Code) func(error) *Error {
 		}
 	}
 }
+
+// Is reports whether any error in err's chain matches
+// target. See the standard library's errors.Is.
+func Is(err, target error) bool {
+	return errors.Is(err, target)
+}
+
+// As finds the first error in err's chain that matches
+// target. See the standard library's errors.As.
+func As(err error, target interface{}) bool {
+	return errors.As(err, target)
+}
@@ -0,0 +1,188 @@
+package index
+
+import (
+	"context"
+	"log"
+
+	"github.com/namvu9/keylime/src/errors"
+)
+
+// BulkInsert inserts a batch of records into the index. If
+// the records are sorted in strictly ascending order and
+// every key is greater than the largest key in the index,
+// the tree is extended bottom-up along its right spine.
+// Otherwise, the records are inserted one by one.
+func (index *Index) BulkInsert(ctx context.Context, records []Record) error {
+	const op errors.Op = "(*Index).BulkInsert"
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	spine, err := index.rightSpine()
+	if err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	if !appendable(spine[0], records) {
+		log.Printf("Index: bulk inserting %d unsorted records\n", len(records))
+		for _, r := range records {
+			if err := index.insert(ctx, r); err != nil {
+				return errors.Wrap(op, errors.EInternal, err)
+			}
+		}
+
+		return nil
+	}
+
+	log.Printf("Index: appending %d sorted records\n", len(records))
+	for _, r := range records {
+		spine, err = index.appendRecord(spine, 0, r, "")
+		if err != nil {
+			return errors.Wrap(op, errors.EInternal, err)
+		}
+
+		index.Records++
+	}
+
+	if err := index.balanceRightSpine(); err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	return nil
+}
+
+// appendable reports whether `records` are sorted in
+// strictly ascending order and can be appended after the
+// largest key in the tree, whose rightmost leaf is `leaf`.
+func appendable(leaf *Node, records []Record) bool {
+	prev := records[0]
+	if n := len(leaf.Records); n > 0 && !leaf.Records[n-1].IsLessThan(prev) {
+		return false
+	}
+
+	for _, r := range records[1:] {
+		if !prev.IsLessThan(r) {
+			return false
+		}
+		prev = r
+	}
+
+	return true
+}
+
+// rightSpine returns the path from the rightmost leaf to
+// the root, i.e. spine[0] is the rightmost leaf and
+// spine[len(spine)-1] is the root.
+func (index *Index) rightSpine() ([]*Node, error) {
+	node, err := index.root()
+	if err != nil {
+		return nil, err
+	}
+
+	spine := []*Node{node}
+	for !node.Leaf {
+		node, err = node.child(len(node.Children) - 1)
+		if err != nil {
+			return nil, err
+		}
+
+		spine = append([]*Node{node}, spine...)
+	}
+
+	return spine, nil
+}
+
+// appendRecord appends `r` to the rightmost node at height
+// `level`. For internal nodes, `child` is the ID of the
+// node to the right of `r`. If the node is full, a new
+// sibling takes its place on the spine and `r` is pushed
+// up as the separator between the two, growing a new root
+// if necessary.
+func (index *Index) appendRecord(spine []*Node, level int, r Record, child string) ([]*Node, error) {
+	node := spine[level]
+
+	if !node.full() {
+		node.Records = append(node.Records, r)
+		if child != "" {
+			node.Children = append(node.Children, child)
+		}
+
+		return spine, node.save()
+	}
+
+	sibling, err := index.New(node.Leaf)
+	if err != nil {
+		return nil, err
+	}
+
+	if child != "" {
+		sibling.Children = []string{child}
+	}
+
+	if err := sibling.save(); err != nil {
+		return nil, err
+	}
+
+	if level == len(spine)-1 {
+		root, err := index.New(false)
+		if err != nil {
+			return nil, err
+		}
+
+		root.Children = []string{node.ID()}
+		index.RootID = root.ID()
+		index.Height++
+
+		spine = append(spine, root)
+	}
+
+	spine[level] = sibling
+	return index.appendRecord(spine, level+1, r, sibling.ID())
+}
+
+// balanceRightSpine restores the minimum occupancy of the
+// nodes along the right spine, which may be left sparse
+// after appending records to it, by rotating keys from or
+// merging with their left siblings.
+func (index *Index) balanceRightSpine() error {
+	root, err := index.root()
+	if err != nil {
+		return err
+	}
+
+	for node := root; !node.Leaf; {
+		child, err := node.child(len(node.Children) - 1)
+		if err != nil {
+			return err
+		}
+
+		for child.sparse() && len(node.Children) > 1 {
+			handleSparseNode(node, child)
+
+			child, err = node.child(len(node.Children) - 1)
+			if err != nil {
+				return err
+			}
+		}
+
+		node = child
+	}
+
+	for root.empty() && !root.Leaf {
+		newRoot, err := root.child(0)
+		if err != nil {
+			return err
+		}
+
+		if err := root.deleteNode(); err != nil {
+			return err
+		}
+
+		index.RootID = newRoot.ID()
+		index.Height--
+		root = newRoot
+	}
+
+	return nil
+}
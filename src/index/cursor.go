@@ -0,0 +1,162 @@
+package index
+
+import (
+	"context"
+	"strings"
+)
+
+// A Cursor iterates over the records of an index in key
+// order. Nodes are loaded as the cursor reaches them.
+type Cursor struct {
+	ctx   context.Context
+	asc   bool
+	stack []frame
+	rec   Record
+	err   error
+}
+
+// A frame is a node on the path from the root to the
+// cursor's position and the index of the next record to
+// visit in it. The child subtree preceding that record in
+// the direction of iteration has already been entered.
+type frame struct {
+	node *Node
+	i    int
+}
+
+// Cursor returns a cursor over the records in the index in
+// ascending key order if `asc` is true and in descending
+// order otherwise. If `after` is not empty, iteration
+// starts at the first key that follows it in that order.
+func (index *Index) Cursor(ctx context.Context, asc bool, after string) (*Cursor, error) {
+	root, err := index.root()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cursor{ctx: ctx, asc: asc}
+	for node := root; ; {
+		i := c.start(node, after)
+		c.stack = append(c.stack, frame{node, i})
+
+		if node.Leaf {
+			break
+		}
+
+		if !asc {
+			i++
+		}
+
+		node, err = node.child(i)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// start returns the index of the first record in `node` to
+// visit when resuming after key `k`
+func (c *Cursor) start(node *Node, k string) int {
+	if k == "" {
+		if c.asc {
+			return 0
+		}
+		return len(node.Records) - 1
+	}
+
+	for i, r := range node.Records {
+		cmp := strings.Compare(r.Key, k)
+		if c.asc && cmp > 0 {
+			return i
+		}
+		if !c.asc && cmp >= 0 {
+			return i - 1
+		}
+	}
+
+	if c.asc {
+		return len(node.Records)
+	}
+	return len(node.Records) - 1
+}
+
+// Next advances the cursor to the next record and reports
+// whether there is one. It returns false if the context is
+// cancelled or a node cannot be loaded, in which case Err
+// returns the cause.
+func (c *Cursor) Next() bool {
+	for len(c.stack) > 0 {
+		if err := c.ctx.Err(); err != nil {
+			c.err = err
+			return false
+		}
+
+		top := &c.stack[len(c.stack)-1]
+		if top.i < 0 || top.i >= len(top.node.Records) {
+			c.stack = c.stack[:len(c.stack)-1]
+			continue
+		}
+
+		c.rec = top.node.Records[top.i]
+
+		if c.asc {
+			top.i++
+		} else {
+			top.i--
+		}
+
+		if !top.node.Leaf {
+			child := top.i
+			if !c.asc {
+				child++
+			}
+
+			if err := c.descend(top.node, child); err != nil {
+				c.err = err
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// descend pushes the path from child `i` of `node` to the
+// first leaf in the direction of iteration
+func (c *Cursor) descend(node *Node, i int) error {
+	for !node.Leaf {
+		child, err := node.child(i)
+		if err != nil {
+			return err
+		}
+
+		node = child
+		i = 0
+		if !c.asc {
+			i = len(node.Children) - 1
+		}
+
+		pos := 0
+		if !c.asc {
+			pos = len(node.Records) - 1
+		}
+
+		c.stack = append(c.stack, frame{node, pos})
+	}
+
+	return nil
+}
+
+// Value returns the current record
+func (c *Cursor) Value() Record {
+	return c.rec
+}
+
+// Err returns the error that stopped the iteration, if any
+func (c *Cursor) Err() error {
+	return c.err
+}
@@ -23,7 +23,7 @@ type Index struct {
 }
 
 func (index *Index) SetRepo(r repository.Repository) {
-	index.repo = repository.WithFactory(r, NodeFactory{t: 200, repo: r})
+	index.repo = repository.WithFactory(r, NodeFactory{t: index.T, repo: r})
 }
 
 func (index *Index) Insert(ctx context.Context, key string, value string, hash string) error {
@@ -64,12 +64,16 @@ func (index *Index) insert(ctx context.Context, ref Record) error {
 		return errors.Wrap(op, errors.EInternal, err)
 	}
 
+	_, exists := node.keyIndex(ref.Key)
+
 	err = node.insert(ref)
 	if err != nil {
 		return err
 	}
 
-	index.Records++
+	if !exists {
+		index.Records++
+	}
 
 	log.Printf("Index: done inserting %s\n", ref)
 	return nil
@@ -154,15 +158,84 @@ func (index *Index) New(leaf bool) (*Node, error) {
 	return node, nil
 }
 
-func (index *Index) Info() string {
-	var sb strings.Builder
+// Drop queues every node of the tree for deletion. The
+// nodes are deleted when the repository is flushed.
+func (index *Index) Drop() error {
+	root, err := index.root()
+	if err != nil {
+		return err
+	}
 
-	sb.WriteString("<Index>\n")
-	sb.WriteString(fmt.Sprintf("Height: %d\n", index.Height))
-	sb.WriteString(fmt.Sprintf("T: %d\n", index.T))
-	sb.WriteString(fmt.Sprintf("Docs: %d\n", index.Records))
+	var walk func(n *Node) error
+	walk = func(n *Node) error {
+		for i := range n.Children {
+			child, err := n.child(i)
+			if err != nil {
+				return err
+			}
+
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
 
-	return sb.String()
+		return n.deleteNode()
+	}
+
+	return walk(root)
+}
+
+// Info walks the tree and returns its stats
+func (index *Index) Info() (types.IndexStats, error) {
+	stats := types.IndexStats{
+		T:       index.T,
+		Height:  index.Height,
+		Records: index.Records,
+	}
+
+	root, err := index.root()
+	if err != nil {
+		return stats, err
+	}
+
+	var (
+		records int
+		walk    func(n *Node) error
+	)
+
+	walk = func(n *Node) error {
+		size, err := index.repo.Size(n.ID())
+		if err != nil {
+			return err
+		}
+
+		stats.Nodes++
+		stats.Bytes += size
+		records += len(n.Records)
+
+		for i := range n.Children {
+			child, err := n.child(i)
+			if err != nil {
+				return err
+			}
+
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := walk(root); err != nil {
+		return stats, err
+	}
+
+	if capacity := stats.Nodes * (2*index.T - 1); capacity > 0 {
+		stats.AverageFill = float64(records) / float64(capacity)
+	}
+
+	return stats, nil
 }
 
 func (index Index) String() string {
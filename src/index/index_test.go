@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math/rand"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/namvu9/keylime/src/repository"
@@ -331,3 +333,158 @@ func BenchmarkInsertKeyIndex(b *testing.B) {
 		})
 	}
 }
+
+func TestBulkInsert(t *testing.T) {
+	ctx := context.Background()
+
+	makeRecords := func(from, to int) []Record {
+		var out []Record
+		for i := from; i < to; i++ {
+			out = append(out, Record{Key: fmt.Sprintf("k%03d", i)})
+		}
+		return out
+	}
+
+	// checkTree verifies that every node except the root
+	// holds between t-1 and 2t-1 records and that all
+	// leaves are at the same depth
+	var checkTree func(t *testing.T, n *Node, depth int, isRoot bool) int
+	checkTree = func(t *testing.T, n *Node, depth int, isRoot bool) int {
+		if !isRoot && (len(n.Records) < n.T-1 || len(n.Records) > 2*n.T-1) {
+			t.Errorf("Node %s has %d records (t=%d)", n.ID(), len(n.Records), n.T)
+		}
+
+		if n.Leaf {
+			return depth
+		}
+
+		if len(n.Children) != len(n.Records)+1 {
+			t.Errorf("Node %s has %d records and %d children", n.ID(), len(n.Records), len(n.Children))
+		}
+
+		leafDepth := -1
+		for i := range n.Children {
+			child, err := n.child(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			d := checkTree(t, child, depth+1, false)
+			if leafDepth != -1 && d != leafDepth {
+				t.Errorf("Leaves at different depths: %d and %d", leafDepth, d)
+			}
+			leafDepth = d
+		}
+
+		return leafDepth
+	}
+
+	for i, test := range []struct {
+		name    string
+		batches [][]Record
+	}{
+		{"Sorted input", [][]Record{makeRecords(0, 100)}},
+		{"Sorted batches", [][]Record{makeRecords(0, 7), makeRecords(7, 50), makeRecords(50, 51)}},
+		{"Unsorted batch", [][]Record{makeRecords(50, 100), makeRecords(0, 50)}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			repo, _ := newMockRepo(2)
+			index := New(2, repo)
+			index.Create()
+
+			want := 0
+			for _, batch := range test.batches {
+				if err := index.BulkInsert(ctx, batch); err != nil {
+					t.Fatalf("%d: Unexpected error: %s", i, err)
+				}
+				want += len(batch)
+			}
+
+			if index.Records != want {
+				t.Errorf("%d: Records, Want=%d Got=%d", i, want, index.Records)
+			}
+
+			for _, batch := range test.batches {
+				for _, r := range batch {
+					if _, err := index.Get(ctx, r.Key); err != nil {
+						t.Errorf("%d: Could not find key %s", i, r.Key)
+					}
+				}
+			}
+
+			root, err := index.root()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := checkTree(t, root, 0, true); got != index.Height {
+				t.Errorf("%d: Height, Want=%d Got=%d", i, got, index.Height)
+			}
+		})
+	}
+}
+
+func TestCursor(t *testing.T) {
+	ctx := context.Background()
+
+	repo, _ := newMockRepo(2)
+	index := New(2, repo)
+	index.Create()
+
+	// Keys k000, k002, ..., k098
+	var keys []string
+	for i := 0; i < 100; i += 2 {
+		keys = append(keys, fmt.Sprintf("k%03d", i))
+	}
+
+	for _, i := range rand.Perm(len(keys)) {
+		index.Insert(ctx, keys[i], "", "")
+	}
+
+	collect := func(asc bool, after string) []string {
+		c, err := index.Cursor(ctx, asc, after)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var out []string
+		for c.Next() {
+			out = append(out, c.Value().Key)
+		}
+
+		if err := c.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		return out
+	}
+
+	reversed := func(s []string) []string {
+		var out []string
+		for i := len(s) - 1; i >= 0; i-- {
+			out = append(out, s[i])
+		}
+		return out
+	}
+
+	for i, test := range []struct {
+		asc   bool
+		after string
+		want  []string
+	}{
+		{true, "", keys},
+		{false, "", reversed(keys)},
+		{true, "k010", keys[6:]},
+		{true, "k011", keys[6:]},
+		{false, "k010", reversed(keys[:5])},
+		{false, "k011", reversed(keys[:6])},
+		{true, "k098", nil},
+		{false, "k000", nil},
+		{true, "a", keys},
+		{false, "z", reversed(keys)},
+	} {
+		if got := collect(test.asc, test.after); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%d: Want %v Got %v", i, test.want, got)
+		}
+	}
+}
@@ -420,6 +420,7 @@ func handleSparseNode(node, child *Node) {
 
 		child.insert(pivot)
 		node.setRecord(recordIndex, siblingRecord)
+		p.Records = p.Records[:len(p.Records)-1]
 
 		if !p.Leaf {
 			// Move child from sibling to child
@@ -437,6 +438,7 @@ func handleSparseNode(node, child *Node) {
 		// Move key from parent to child
 		child.Records = append(child.Records, pivot)
 		node.setRecord(index, siblingRecord)
+		s.Records = s.Records[1:]
 
 		// Move child from sibling to child
 		if !s.Leaf {
@@ -723,6 +723,8 @@ func TestHandleSparseNode(t *testing.T) {
 			}
 
 			nu.withChild(0, func(nu namedUtil) {
+				nu.hasKeys("a")
+
 				if _, ok := reporter.Writes[nu.node.ID()]; !ok {
 					t.Errorf("Left child not written")
 				}
@@ -813,6 +815,8 @@ func TestHandleSparseNode(t *testing.T) {
 			})
 
 			nu.withChild(1, func(nu namedUtil) {
+				nu.hasKeys("e")
+
 				if _, ok := reporter.Writes[nu.node.ID()]; !ok {
 					t.Errorf("Right child not written")
 				}
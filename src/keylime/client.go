@@ -19,7 +19,7 @@ func Open(cfg DialConfig) (*Conn, error) {
 		port = cfg.Port
 	}
 
-	conn, err := net.Dial("tcp", fmt.Sprintf("%s:%s", cfg.Host, port))
+	conn, err := net.Dial("tcp", net.JoinHostPort(cfg.Host, port))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,192 @@
+package queries
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/namvu9/keylime/src/types"
+)
+
+var aggregateFuncs = map[string]types.AggregateFunc{
+	"COUNT": types.Count,
+	"SUM":   types.Sum,
+	"AVG":   types.Avg,
+	"MIN":   types.Min,
+	"MAX":   types.Max,
+}
+
+// parsePath parses a field path of the form a.b.c
+func parsePath(p *Parser) ([]string, error) {
+	if p.Peek().Type != IdentifierToken {
+		return nil, fmt.Errorf("Parsing error: Expected field name, but got %v", p.Peek())
+	}
+
+	path := []string{p.Next().Value}
+	for p.Peek().Value == PERIOD {
+		p.Next()
+
+		if p.Peek().Type != IdentifierToken {
+			return nil, fmt.Errorf("Parsing error: Expected field name after PERIOD, but got %v", p.Peek())
+		}
+
+		path = append(path, p.Next().Value)
+	}
+
+	return path, nil
+}
+
+// parseAggregates parses a comma-separated list of
+// aggregates such as COUNT(*), AVG(age)
+func parseAggregates(p *Parser) ([]types.Aggregate, error) {
+	var aggs []types.Aggregate
+
+	for {
+		fn, ok := aggregateFuncs[p.Peek().Value]
+		if !ok {
+			return nil, fmt.Errorf("Parsing error: Expected aggregate function, but got %v", p.Peek())
+		}
+		p.Next()
+
+		if p.Peek().Value != LPAREN {
+			return nil, fmt.Errorf("Parsing error: Expected LPAREN after %s, but got %v", fn, p.Peek())
+		}
+		p.Next()
+
+		agg := types.Aggregate{Func: fn}
+		if p.Peek().Value == STAR && fn == types.Count {
+			p.Next()
+		} else {
+			path, err := parsePath(p)
+			if err != nil {
+				return nil, err
+			}
+			agg.Path = path
+		}
+
+		if p.Peek().Value != RPAREN {
+			return nil, fmt.Errorf("Parsing error: Expected RPAREN, but got %v", p.Peek())
+		}
+		p.Next()
+
+		aggs = append(aggs, agg)
+
+		if p.Peek().Value != COMMA {
+			return aggs, nil
+		}
+		p.Next()
+	}
+}
+
+// parseOrderBy parses a comma-separated list of sort keys
+// of the form `path [ASC|DESC]`
+func parseOrderBy(p *Parser) (types.OrderBy, error) {
+	var orderBy types.OrderBy
+
+	for {
+		path, err := parsePath(p)
+		if err != nil {
+			return nil, err
+		}
+
+		sk := types.SortKey{Path: path}
+		switch p.Peek().Value {
+		case "DESC":
+			sk.Desc = true
+			p.Next()
+		case "ASC":
+			p.Next()
+		}
+
+		orderBy = append(orderBy, sk)
+
+		if p.Peek().Value != COMMA {
+			return orderBy, nil
+		}
+		p.Next()
+	}
+}
+
+// parseFilter parses conditions of the form `path op value`
+// joined by AND
+func parseFilter(p *Parser) (types.Filter, error) {
+	var filter types.Filter
+
+	for {
+		path, err := parsePath(p)
+		if err != nil {
+			return nil, err
+		}
+
+		op, err := parseOperator(p)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := parseValue(p.Next())
+		if err != nil {
+			return nil, err
+		}
+
+		filter = append(filter, types.Condition{Path: path, Op: op, Value: value})
+
+		if p.Peek().Value != "AND" {
+			return filter, nil
+		}
+		p.Next()
+	}
+}
+
+// parseOperator parses a comparison operator, which may
+// span two delimiter tokens
+func parseOperator(p *Parser) (types.Operator, error) {
+	tok := p.Next()
+	if tok.Type != DelimiterToken {
+		return "", fmt.Errorf("Parsing error: Expected comparison operator, but got %v", tok)
+	}
+
+	switch tok.Value {
+	case EQUALS:
+		return types.Eq, nil
+	case BANG:
+		if p.Peek().Value != EQUALS {
+			return "", fmt.Errorf("Parsing error: Expected EQUALS after BANG, but got %v", p.Peek())
+		}
+		p.Next()
+		return types.Ne, nil
+	case LESS, GREATER:
+		op := types.Operator(tok.Value)
+		if p.Peek().Value == EQUALS {
+			p.Next()
+			op += EQUALS
+		}
+		return op, nil
+	default:
+		return "", fmt.Errorf("Parsing error: Expected comparison operator, but got %v", tok)
+	}
+}
+
+// parseValue returns the field represented by a literal
+func parseValue(tok Token) (types.Field, error) {
+	var v interface{}
+
+	switch tok.Type {
+	case NumberValue:
+		n, err := types.ParseNumber(tok.Value)
+		if err != nil {
+			return types.Field{}, err
+		}
+		v = n
+	case BooleanValue:
+		b, err := strconv.ParseBool(tok.Value)
+		if err != nil {
+			return types.Field{}, err
+		}
+		v = b
+	case StringValue:
+		v = tok.Value
+	default:
+		return types.Field{}, fmt.Errorf("Parsing error: Expected Number, String or Boolean value, but got %v", tok)
+	}
+
+	return types.Field{Type: types.GetDataType(v), Value: v}, nil
+}
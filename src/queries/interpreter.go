@@ -2,9 +2,13 @@ package queries
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/namvu9/keylime/src/errors"
 	"github.com/namvu9/keylime/src/types"
@@ -36,14 +40,26 @@ func Interpret(ctx context.Context, s types.Store, input string) (interface{}, e
 type cmdHandler func(context.Context, types.Store, Operation) (interface{}, error)
 
 var handlers = map[Command]cmdHandler{
-	Get:    handleGet,
-	Set:    handleSet,
-	Update: handleUpdate,
-	Create: handleCreate,
-	Delete: handleDelete,
-	First:  handleFirst,
-	Last:   handleLast,
-	Info:   handleInfo,
+	Get:      handleGet,
+	Set:      handleSet,
+	Upsert:   handleUpsert,
+	Update:   handleUpdate,
+	Create:   handleCreate,
+	Delete:   handleDelete,
+	First:    handleFirst,
+	Last:     handleLast,
+	Info:     handleInfo,
+	Load:     handleLoad,
+	MSet:     handleMSet,
+	MGet:     handleMGet,
+	History:  handleHistory,
+	Select:   handleSelect,
+	Count:    handleCount,
+	Insert:   handleInsert,
+	Describe: handleDescribe,
+	Show:     handleShow,
+	Drop:     handleDrop,
+	Rename:   handleRename,
 }
 
 func handleGet(ctx context.Context, s types.Store, op Operation) (interface{}, error) {
@@ -53,16 +69,45 @@ func handleGet(ctx context.Context, s types.Store, op Operation) (interface{}, e
 	}
 
 	key := op.Arguments["key"]
-	rec, err := c.Get(ctx, key)
-	if err != nil {
+
+	var rec *types.Document
+	if revision, ok := op.Arguments["revision"]; ok {
+		rev, err := strconv.Atoi(revision)
+		if err != nil {
+			return nil, err
+		}
+
+		rec, err = c.GetRevision(ctx, key, rev)
+	} else {
+		rec, err = c.Get(ctx, key)
+	}
+
+	if errors.Is(err, errors.ErrCollectionNotFound) {
+		return nil, err
+	} else if err != nil {
 		werr := errors.Wrap("(*types.Store).Run", errors.ENotFound, fmt.Errorf("%w in %s", err, op.Collection))
 		werr.Collection = op.Collection
 
 		return nil, werr
 	}
 
-	if selectors, ok := op.Arguments["selectors"]; ok {
-		res := rec.Select(types.MakeFieldSelectors(selectors)...)
+	if fields, ok := op.Payload.Data["expand"].([]string); ok {
+		expanded, err := c.Expand(ctx, *rec, fields...)
+		if err != nil {
+			return nil, err
+		}
+		rec = &expanded
+	}
+
+	if ps, ok := op.Payload.Data["projection"].([]types.Projection); ok {
+		res, err := rec.Project(ps, types.ProjectOptions{
+			Strict: op.Arguments["strict"] == "true",
+			Typed:  op.Arguments["typed"] == "true",
+		})
+		if err != nil {
+			return nil, errors.Wrap("(*types.Store).Run", errors.ENotFound, err)
+		}
+
 		return res, nil
 	}
 
@@ -78,7 +123,12 @@ func handleSet(ctx context.Context, s types.Store, op Operation) (interface{}, e
 	key := op.Arguments["key"]
 	fields := op.Payload.Data
 
-	err = c.Set(ctx, key, fields)
+	opts, err := documentOptions(op)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.Set(ctx, key, fields, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -86,6 +136,104 @@ func handleSet(ctx context.Context, s types.Store, op Operation) (interface{}, e
 	return nil, nil
 }
 
+// handleInsert writes a document under a generated key and
+// returns the key
+func handleInsert(ctx context.Context, s types.Store, op Operation) (interface{}, error) {
+	c, err := s.Collection(op.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := documentOptions(op)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := c.Insert(ctx, op.Payload.Data, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{"key": key}, nil
+}
+
+// documentOptions returns the document options given by the
+// arguments of `op`
+func documentOptions(op Operation) ([]types.DocumentOption, error) {
+	var opts []types.DocumentOption
+
+	if ttl, ok := op.Arguments["ttl"]; ok {
+		d, err := parseTTL(ttl)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, types.WithTTL(d))
+	}
+
+	return opts, nil
+}
+
+// parseTTL parses a time to live given in seconds
+func parseTTL(s string) (time.Duration, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(n) * time.Second, nil
+}
+
+func handleUpsert(ctx context.Context, s types.Store, op Operation) (interface{}, error) {
+	c, err := s.Collection(op.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	key := op.Arguments["key"]
+	fields := op.Payload.Data
+
+	opts, err := documentOptions(op)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, c.Upsert(ctx, key, fields, opts...)
+}
+
+func handleMSet(ctx context.Context, s types.Store, op Operation) (interface{}, error) {
+	c, err := s.Collection(op.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make(map[string]map[string]interface{})
+	for key, value := range op.Payload.Data {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, errors.Wrap("handleMSet", errors.EBadRequest, fmt.Errorf("Expected an object for key %s but got %v", key, value))
+		}
+
+		docs[key] = fields
+	}
+
+	mode := types.AllOrNothing
+	if op.Arguments["partial"] == "true" {
+		mode = types.Partial
+	}
+
+	return nil, c.SetMany(ctx, docs, mode)
+}
+
+func handleMGet(ctx context.Context, s types.Store, op Operation) (interface{}, error) {
+	c, err := s.Collection(op.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetMany(ctx, strings.Fields(op.Arguments["keys"]))
+}
+
 func handleUpdate(ctx context.Context, s types.Store, op Operation) (interface{}, error) {
 	var (
 		key    = op.Arguments["key"]
@@ -97,6 +245,14 @@ func handleUpdate(ctx context.Context, s types.Store, op Operation) (interface{}
 		return nil, err
 	}
 
+	if ops, ok := fields["ops"].([]types.UpdateOp); ok {
+		return nil, c.ApplyIf(ctx, key, op.Arguments["version"], ops)
+	}
+
+	if version, ok := op.Arguments["version"]; ok {
+		return nil, c.UpdateIf(ctx, key, version, fields)
+	}
+
 	err = c.Update(ctx, key, fields)
 
 	return nil, err
@@ -108,19 +264,49 @@ func handleCreate(ctx context.Context, s types.Store, op Operation) (interface{}
 		return nil, err
 	}
 
-	if schema, ok := op.Payload.Data["schema"]; ok {
-		err := c.Create(ctx, schema.(*types.Schema))
+	var opts []types.CollectionOption
+	if history, ok := op.Arguments["history"]; ok {
+		n, err := strconv.Atoi(history)
 		if err != nil {
 			return nil, err
 		}
-	} else {
-		err := c.Create(ctx, nil)
+
+		opts = append(opts, types.WithHistory(n))
+	}
+
+	if ttl, ok := op.Arguments["ttl"]; ok {
+		d, err := parseTTL(ttl)
 		if err != nil {
 			return nil, err
 		}
+
+		opts = append(opts, types.WithDefaultTTL(d))
 	}
 
-	return nil, nil
+	if keys, ok := op.Arguments["keys"]; ok {
+		opts = append(opts, types.WithKeys(types.KeyStrategy(keys)))
+	}
+
+	var schema *types.Schema
+	if s, ok := op.Payload.Data["schema"]; ok {
+		schema = s.(*types.Schema)
+	}
+
+	err = c.Create(ctx, schema, opts...)
+	if errors.Is(err, errors.ErrCollectionExists) && op.Arguments["ifNotExists"] == "true" {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+func handleHistory(ctx context.Context, s types.Store, op Operation) (interface{}, error) {
+	c, err := s.Collection(op.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.History(ctx, op.Arguments["key"])
 }
 
 func handleDelete(ctx context.Context, s types.Store, op Operation) (interface{}, error) {
@@ -133,7 +319,12 @@ func handleDelete(ctx context.Context, s types.Store, op Operation) (interface{}
 		return nil, err
 	}
 
-	err = c.Delete(ctx, key)
+	if version, ok := op.Arguments["version"]; ok {
+		err = c.DeleteIf(ctx, key, version)
+	} else {
+		err = c.Delete(ctx, key)
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -152,7 +343,12 @@ func handleFirst(ctx context.Context, s types.Store, op Operation) (interface{},
 		return nil, err
 	}
 
-	return c.GetFirst(ctx, int(n))
+	opts, err := listOptions(op)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetFirst(ctx, int(n), opts)
 }
 
 func handleLast(ctx context.Context, s types.Store, op Operation) (interface{}, error) {
@@ -166,7 +362,60 @@ func handleLast(ctx context.Context, s types.Store, op Operation) (interface{},
 		return nil, err
 	}
 
-	return c.GetLast(ctx, int(n))
+	opts, err := listOptions(op)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetLast(ctx, int(n), opts)
+}
+
+// listOptions returns the pagination options of a FIRST or
+// LAST operation
+func listOptions(op Operation) (types.ListOptions, error) {
+	opts := types.ListOptions{
+		After: op.Arguments["after"],
+		ByKey: op.Arguments["order"] == "key",
+	}
+	opts.OrderBy, _ = op.Payload.Data["orderBy"].(types.OrderBy)
+
+	if skip, ok := op.Arguments["skip"]; ok {
+		n, err := strconv.Atoi(skip)
+		if err != nil {
+			return opts, err
+		}
+		opts.Skip = n
+	}
+
+	return opts, nil
+}
+
+func handleSelect(ctx context.Context, s types.Store, op Operation) (interface{}, error) {
+	c, err := s.Collection(op.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	q := types.AggregateQuery{Where: filter(op)}
+	q.Aggregates, _ = op.Payload.Data["aggregates"].([]types.Aggregate)
+	q.GroupBy, _ = op.Payload.Data["groupBy"].([][]string)
+
+	return c.Aggregate(ctx, q)
+}
+
+func handleCount(ctx context.Context, s types.Store, op Operation) (interface{}, error) {
+	c, err := s.Collection(op.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Count(ctx, filter(op))
+}
+
+// filter returns the WHERE clause of an operation
+func filter(op Operation) types.Filter {
+	f, _ := op.Payload.Data["where"].(types.Filter)
+	return f
 }
 
 func handleInfo(ctx context.Context, s types.Store, op Operation) (interface{}, error) {
@@ -175,6 +424,117 @@ func handleInfo(ctx context.Context, s types.Store, op Operation) (interface{},
 		return nil, err
 	}
 
-	info := c.Info(ctx)
+	info, err := c.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if op.Arguments["format"] == "text" {
+		return info.String(), nil
+	}
+
 	return info, nil
 }
+
+func handleDescribe(ctx context.Context, s types.Store, op Operation) (interface{}, error) {
+	c, err := s.Collection(op.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := c.GetSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if op.Arguments["format"] == "jsonschema" {
+		return schema.JSONSchema(), nil
+	}
+
+	return map[string]interface{}{
+		"collection": op.Collection,
+		"fields":     schema.Describe(),
+	}, nil
+}
+
+func handleShow(ctx context.Context, s types.Store, op Operation) (interface{}, error) {
+	names, err := s.Collections(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if names == nil {
+		names = []string{}
+	}
+
+	return names, nil
+}
+
+func handleDrop(ctx context.Context, s types.Store, op Operation) (interface{}, error) {
+	return nil, s.Drop(ctx, op.Collection)
+}
+
+func handleRename(ctx context.Context, s types.Store, op Operation) (interface{}, error) {
+	return nil, s.Rename(ctx, op.Collection, op.Arguments["to"])
+}
+
+func handleLoad(ctx context.Context, s types.Store, op Operation) (interface{}, error) {
+	c, err := s.Collection(op.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := s.OpenImport(op.Arguments["path"])
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return c.BulkLoad(ctx, newJSONDocIterator(f))
+}
+
+// jsonDocIterator reads a stream of JSON objects of the form
+// {"key": "k", "fields": {...}}, such as a file with one
+// object per line, and yields them as documents.
+type jsonDocIterator struct {
+	dec *json.Decoder
+	doc types.Document
+	err error
+}
+
+func (it *jsonDocIterator) Next() bool {
+	var entry struct {
+		Key    string                 `json:"key"`
+		Fields map[string]interface{} `json:"fields"`
+	}
+
+	if err := it.dec.Decode(&entry); err != nil {
+		if err != io.EOF {
+			it.err = err
+		}
+		return false
+	}
+
+	if entry.Key == "" {
+		it.err = fmt.Errorf("Document is missing a key")
+		return false
+	}
+
+	it.doc = types.NewDoc(entry.Key).Set(entry.Fields)
+	return true
+}
+
+func (it *jsonDocIterator) Value() types.Document {
+	return it.doc
+}
+
+func (it *jsonDocIterator) Err() error {
+	return it.err
+}
+
+func newJSONDocIterator(r io.Reader) *jsonDocIterator {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	return &jsonDocIterator{dec: dec}
+}
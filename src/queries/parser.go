@@ -3,19 +3,33 @@ package queries
 import (
 	"fmt"
 	"strings"
+
+	"github.com/namvu9/keylime/src/types"
 )
 
 type Command string
 
 const (
-	Set    Command = "Set"
-	Get            = "Get"
-	Update         = "Update"
-	Info           = "Info"
-	Create         = "Create"
-	Last           = "Last"
-	First          = "First"
-	Delete         = "Delete"
+	Set      Command = "Set"
+	Upsert           = "Upsert"
+	Get              = "Get"
+	Update           = "Update"
+	Info             = "Info"
+	Create           = "Create"
+	Last             = "Last"
+	First            = "First"
+	Delete           = "Delete"
+	Load             = "Load"
+	MSet             = "MSet"
+	MGet             = "MGet"
+	History          = "History"
+	Select           = "Select"
+	Count            = "Count"
+	Insert           = "Insert"
+	Describe         = "Describe"
+	Show             = "Show"
+	Drop             = "Drop"
+	Rename           = "Rename"
 )
 
 type Operation struct {
@@ -50,7 +64,7 @@ func (p *Parser) Parse() (Operation, error) {
 		case "FIRST":
 			p.op.Command = First
 
-			if p.Peek().Type != NumberValue {
+			if !isCount(p.Peek()) {
 				return *p.op, fmt.Errorf("Parsing error: Expected Number token after FIRST but got %v", p.Peek())
 			}
 
@@ -63,7 +77,7 @@ func (p *Parser) Parse() (Operation, error) {
 		case "LAST":
 			p.op.Command = Last
 
-			if p.Peek().Type != NumberValue {
+			if !isCount(p.Peek()) {
 				return *p.op, fmt.Errorf("Parsing error: Expected Number token after LAST but got %v", p.Peek())
 			}
 			n := p.Next()
@@ -73,6 +87,41 @@ func (p *Parser) Parse() (Operation, error) {
 				return *p.op, fmt.Errorf("expected IN token after LAST but got %s", p.Peek().Value)
 			}
 
+		case "AFTER":
+			if p.Peek().Type != IdentifierToken && p.Peek().Type != StringValue {
+				return *p.op, fmt.Errorf("Parsing error: Expected cursor after AFTER, but got %v", p.Peek())
+			}
+
+			next := p.Next()
+			p.op.Arguments["after"] = next.Value
+
+		case "SKIP", "OFFSET":
+			if !isCount(p.Peek()) {
+				return *p.op, fmt.Errorf("Parsing error: Expected Number token after %s, but got %v", token.Value, p.Peek())
+			}
+
+			next := p.Next()
+			p.op.Arguments["skip"] = next.Value
+
+		case "ORDER":
+			if p.Peek().Value != "BY" {
+				return *p.op, fmt.Errorf("Parsing error: Expected BY after ORDER, but got %v", p.Peek())
+			}
+			p.Next()
+
+			if p.Peek().Value == "KEY" {
+				p.Next()
+				p.op.Arguments["order"] = "key"
+				break
+			}
+
+			orderBy, err := parseOrderBy(p)
+			if err != nil {
+				return *p.op, err
+			}
+
+			p.setData("orderBy", orderBy)
+
 		case "DELETE":
 			p.op.Command = Delete
 
@@ -84,12 +133,103 @@ func (p *Parser) Parse() (Operation, error) {
 
 			p.op.Arguments["key"] = next.Value
 
+		case "AT":
+			if p.Peek().Value != "REVISION" {
+				return *p.op, fmt.Errorf("Parsing error: Expected REVISION after AT, but got %v", p.Peek())
+			}
+			p.Next()
+
+			if !isCount(p.Peek()) {
+				return *p.op, fmt.Errorf("Parsing error: Expected Number token after REVISION, but got %v", p.Peek())
+			}
+
+			next := p.Next()
+			p.op.Arguments["revision"] = next.Value
+
+		case "HISTORY":
+			p.op.Command = History
+
+			if p.Peek().Type != IdentifierToken {
+				return *p.op, fmt.Errorf("Parsing error: Expected Identifier token after HISTORY, but got =%v", p.Peek())
+			}
+
+			next := p.Next()
+			p.op.Arguments["key"] = next.Value
+
+		case "TTL":
+			if !isCount(p.Peek()) {
+				return *p.op, fmt.Errorf("Parsing error: Expected Number token after TTL, but got %v", p.Peek())
+			}
+
+			next := p.Next()
+			p.op.Arguments["ttl"] = next.Value
+
+		case "IF":
+			if p.Peek().Value != "VERSION" {
+				return *p.op, fmt.Errorf("Parsing error: Expected VERSION after IF, but got %v", p.Peek())
+			}
+			p.Next()
+
+			if p.Peek().Value != EQUALS {
+				return *p.op, fmt.Errorf("Parsing error: Expected EQUALS after VERSION, but got %v", p.Peek())
+			}
+			p.Next()
+
+			if p.Peek().Type != StringValue {
+				return *p.op, fmt.Errorf("Parsing error: Expected String token after EQUALS, but got %v", p.Peek())
+			}
+
+			next := p.Next()
+			p.op.Arguments["version"] = next.Value
+
+		case "LOAD":
+			p.op.Command = Load
+
+			if p.Peek().Type != StringValue {
+				return *p.op, fmt.Errorf("Parsing error: Expected String token after LOAD, but got %v", p.Peek())
+			}
+
+			next := p.Next()
+			p.op.Arguments["path"] = next.Value
+
+			if p.Peek().Value != "IN" {
+				return *p.op, fmt.Errorf("expected IN token after LOAD but got %s", p.Peek().Value)
+			}
+
 		case "WITH":
-			if p.Peek().Type != StringValue && p.Peek().Value != "SCHEMA" {
+			if p.Peek().Type != StringValue && p.Peek().Value != "SCHEMA" && p.Peek().Value != "HISTORY" && p.Peek().Value != "JSON" {
 				return *p.op, fmt.Errorf("Parsing error: Expected StringValue token after WITH, but got %s", p.Peek().Type)
 			}
 
-			if p.Peek().Type == KeywordToken && p.Peek().Value == "SCHEMA" {
+			if p.Peek().Type == KeywordToken && p.Peek().Value == "HISTORY" {
+				p.Next()
+
+				if !isCount(p.Peek()) {
+					return *p.op, fmt.Errorf("Parsing error: Expected Number token after HISTORY, but got %v", p.Peek())
+				}
+
+				next := p.Next()
+				p.op.Arguments["history"] = next.Value
+			} else if p.Peek().Type == KeywordToken && p.Peek().Value == "JSON" {
+				// WITH JSON SCHEMA '<document>'
+				p.Next()
+				if p.Peek().Value != "SCHEMA" {
+					return *p.op, fmt.Errorf("Parsing error: Expected SCHEMA after JSON, but got %v", p.Peek())
+				}
+				p.Next()
+
+				if p.Peek().Type != StringValue {
+					return *p.op, fmt.Errorf("Parsing error: Expected String token after JSON SCHEMA, but got %v", p.Peek())
+				}
+
+				next := p.Next()
+				schema, err := types.FromJSONSchema([]byte(next.Value))
+				if err != nil {
+					return *p.op, fmt.Errorf("Parsing error: %w", err)
+				}
+
+				p.setData("schema", &schema)
+			} else if p.Peek().Type == KeywordToken && p.Peek().Value == "SCHEMA" {
 				p.Next()
 				p.Next()
 				schema, err := parseSchema(p)
@@ -112,6 +252,16 @@ func (p *Parser) Parse() (Operation, error) {
 		case "CREATE":
 			p.op.Command = Create
 
+			// CREATE IF NOT EXISTS <collection>
+			if p.Peek().Value == "IF" {
+				p.Next()
+				if p.Next().Value != "NOT" || p.Next().Value != "EXISTS" {
+					return *p.op, fmt.Errorf("Parsing error: Expected NOT EXISTS after CREATE IF")
+				}
+
+				p.op.Arguments["ifNotExists"] = "true"
+			}
+
 			if p.Peek().Type != IdentifierToken {
 				return *p.op, fmt.Errorf("Parsing error: Expected Identifier token after CREATE, but got =%v", p.Peek())
 			}
@@ -129,14 +279,97 @@ func (p *Parser) Parse() (Operation, error) {
 			next := p.Next()
 			p.op.Collection = next.Value
 
-		case "SET", "UPDATE":
+			// INFO <collection> AS TEXT renders the stats for
+			// people rather than as JSON
+			if p.Peek().Value == "AS" {
+				p.Next()
+				if p.Next().Value != "TEXT" {
+					return *p.op, fmt.Errorf("Parsing error: Expected TEXT after AS")
+				}
+
+				p.op.Arguments["format"] = "text"
+			}
+
+		case "SHOW":
+			p.op.Command = Show
+
+			if p.Peek().Value != "COLLECTIONS" {
+				return *p.op, fmt.Errorf("Parsing error: Expected COLLECTIONS after SHOW, but got %v", p.Peek())
+			}
+			p.Next()
+
+		case "DROP":
+			p.op.Command = Drop
+
+			if p.Peek().Type != IdentifierToken {
+				return *p.op, fmt.Errorf("Parsing error: Expected Identifier token after DROP, but got %v", p.Peek())
+			}
+
+			next := p.Next()
+			p.op.Collection = next.Value
+
+		case "RENAME":
+			// RENAME <collection> TO <name>
+			p.op.Command = Rename
+
+			if p.Peek().Type != IdentifierToken {
+				return *p.op, fmt.Errorf("Parsing error: Expected Identifier token after RENAME, but got %v", p.Peek())
+			}
+
+			next := p.Next()
+			p.op.Collection = next.Value
+
+			if p.Peek().Value != "TO" {
+				return *p.op, fmt.Errorf("Parsing error: Expected TO after RENAME %s, but got %v", next.Value, p.Peek())
+			}
+			p.Next()
+
+			if p.Peek().Type != IdentifierToken {
+				return *p.op, fmt.Errorf("Parsing error: Expected Identifier token after TO, but got %v", p.Peek())
+			}
+
+			next = p.Next()
+			p.op.Arguments["to"] = next.Value
+
+		case "DESCRIBE":
+			// DESCRIBE <collection> [AS JSON SCHEMA]
+			p.op.Command = Describe
+
+			if p.Peek().Type != IdentifierToken {
+				return *p.op, fmt.Errorf("Parsing error: Expected Identifier token after DESCRIBE, but got %v", p.Peek())
+			}
+
+			next := p.Next()
+			p.op.Collection = next.Value
+
+			if p.Peek().Value == "AS" {
+				p.Next()
+				if p.Next().Value != "JSON" || p.Next().Value != "SCHEMA" {
+					return *p.op, fmt.Errorf("Parsing error: Expected JSON SCHEMA after AS")
+				}
+
+				p.op.Arguments["format"] = "jsonschema"
+			}
+
+		case "SET", "UPSERT", "UPDATE":
+			// UPDATE <key> IN <collection> SET <operators>
+			if token.Value == "SET" && p.op.Command == Update {
+				ops, err := parseUpdateOps(p)
+				if err != nil {
+					return *p.op, err
+				}
+
+				p.setData("ops", ops)
+				break
+			}
+
 			p.op.Command = commands[token.Value]
 
 			if p.Peek().Type != IdentifierToken {
-				return *p.op, fmt.Errorf("Parsing error: Expected Identifier token after SET, but got =%v", p.Peek())
+				return *p.op, fmt.Errorf("Parsing error: Expected Identifier token after %s, but got =%v", token.Value, p.Peek())
 			}
 
-			if len(p.op.Payload.Data) == 0 {
+			if len(p.op.Payload.Data) == 0 && token.Value != "UPDATE" {
 				return *p.op, fmt.Errorf("Parsing error: The %s command requires a payload", token.Value)
 			}
 
@@ -144,40 +377,176 @@ func (p *Parser) Parse() (Operation, error) {
 
 			p.op.Arguments["key"] = next.Value
 
-		case "FROM":
+		case "INSERT":
+			p.op.Command = Insert
+
+			if p.Peek().Value != "INTO" {
+				return *p.op, fmt.Errorf("Parsing error: Expected INTO after INSERT, but got %v", p.Peek())
+			}
+			p.Next()
+
 			if p.Peek().Type != IdentifierToken {
-				return *p.op, fmt.Errorf("Parsing error: Expected Identifier token after FROM, but got =%v", p.Peek().Type)
+				return *p.op, fmt.Errorf("Parsing error: Expected Identifier token after INTO, but got %v", p.Peek())
 			}
 
 			next := p.Next()
-			p.op.Arguments["key"] = next.Value
+			p.op.Collection = next.Value
 
-		case "GET":
-			p.op.Command = Get
+		case "KEYS":
+			if p.Peek().Type != IdentifierToken {
+				return *p.op, fmt.Errorf("Parsing error: Expected key strategy after KEYS, but got %v", p.Peek())
+			}
 
+			next := p.Next()
+			p.op.Arguments["keys"] = next.Value
+
+		case "MSET":
+			p.op.Command = MSet
+
+			if len(p.op.Payload.Data) == 0 {
+				return *p.op, fmt.Errorf("Parsing error: The %s command requires a payload", token.Value)
+			}
+
+			if p.Peek().Value == "PARTIAL" {
+				p.Next()
+				p.op.Arguments["partial"] = "true"
+			}
+
+			if p.Peek().Value != "IN" {
+				return *p.op, fmt.Errorf("expected IN token after MSET but got %s", p.Peek().Value)
+			}
+
+		case "MGET":
+			p.op.Command = MGet
+
+			if p.Peek().Type != IdentifierToken {
+				return *p.op, fmt.Errorf("Parsing error: Expected Identifier token after MGET, but got =%v", p.Peek())
+			}
+
+			keys := []string{p.Next().Value}
+			for p.Peek().Value == COMMA {
+				p.Next()
+
+				if p.Peek().Type != IdentifierToken {
+					return *p.op, fmt.Errorf("Parsing error: Expected Identifier token after COMMA, but got =%v", p.Peek())
+				}
+
+				keys = append(keys, p.Next().Value)
+			}
+
+			if p.Peek().Value != "IN" {
+				return *p.op, fmt.Errorf("expected IN token after MGET but got %s", p.Peek().Value)
+			}
+
+			p.op.Arguments["keys"] = strings.Join(keys, " ")
+
+		case "FROM":
 			if p.Peek().Type != IdentifierToken {
-				return *p.op, fmt.Errorf("Parsing error: Expected Identifier token after GET, but got =%v", p.Peek().Type)
+				return *p.op, fmt.Errorf("Parsing error: Expected Identifier token after FROM, but got =%v", p.Peek().Type)
 			}
 
 			next := p.Next()
 
-			if _, ok := p.op.Arguments["key"]; !ok {
-				p.op.Arguments["key"] = next.Value
+			// SELECT queries select from a collection, whereas
+			// GET selects from a document
+			if p.op.Command == Select {
+				p.op.Collection = next.Value
 			} else {
-				selectors := []string{p.CurrentToken().Value}
+				p.op.Arguments["key"] = next.Value
+			}
 
-				for p.Peek().Type == IdentifierToken {
-					next := p.Next()
-					selectors = append(selectors, next.Value)
+		case "SELECT":
+			p.op.Command = Select
+
+			aggs, err := parseAggregates(p)
+			if err != nil {
+				return *p.op, err
+			}
+
+			p.setData("aggregates", aggs)
+
+			if p.Peek().Value != "FROM" {
+				return *p.op, fmt.Errorf("Parsing error: Expected FROM after SELECT, but got %v", p.Peek())
+			}
+
+		case "COUNT":
+			p.op.Command = Count
+
+			if p.Peek().Value != "IN" {
+				return *p.op, fmt.Errorf("expected IN token after COUNT but got %s", p.Peek().Value)
+			}
+
+		case "WHERE":
+			filter, err := parseFilter(p)
+			if err != nil {
+				return *p.op, err
+			}
+
+			p.setData("where", filter)
+
+		case "GROUP":
+			if p.Peek().Value != "BY" {
+				return *p.op, fmt.Errorf("Parsing error: Expected BY after GROUP, but got %v", p.Peek())
+			}
+			p.Next()
+
+			var groupBy [][]string
+			for {
+				path, err := parsePath(p)
+				if err != nil {
+					return *p.op, err
 				}
 
-				if p.Peek().Value != "IN" {
-					return *p.op, fmt.Errorf("Parsing error: Expected Keyword IN, but got =%v", p.CurrentToken())
+				groupBy = append(groupBy, path)
+
+				if p.Peek().Value != COMMA {
+					break
 				}
+				p.Next()
+			}
+
+			p.setData("groupBy", groupBy)
 
-				p.op.Arguments["selectors"] = strings.Join(selectors, " ")
+		case "EXPAND":
+			var fields []string
+			for {
+				if p.Peek().Type != IdentifierToken {
+					return *p.op, fmt.Errorf("Parsing error: Expected Identifier token after EXPAND, but got %v", p.Peek())
+				}
+
+				fields = append(fields, p.Next().Value)
+
+				if p.Peek().Value != COMMA {
+					break
+				}
+				p.Next()
 			}
 
+			p.setData("expand", fields)
+
+		case "GET":
+			p.op.Command = Get
+
+			ps, err := parseProjection(p)
+			if err != nil {
+				return *p.op, err
+			}
+
+			// GET <key> IN <collection> gets the entire document
+			if _, ok := p.op.Arguments["key"]; !ok && p.Peek().Value != "FROM" {
+				if len(ps) != 1 || len(ps[0].Path) != 1 || ps[0].Name != ps[0].Path.String() {
+					return *p.op, fmt.Errorf("Parsing error: Expected FROM after projection, but got %v", p.Peek())
+				}
+
+				p.op.Arguments["key"] = ps[0].Name
+				break
+			}
+
+			p.setData("projection", ps)
+
+		case "STRICT", "TYPED":
+			p.op.Arguments[strings.ToLower(token.Value)] = "true"
+
 		case "IN":
 			if p.Peek().Type != IdentifierToken {
 				return *p.op, fmt.Errorf("Parsing error: Expected Identifier token after IN, but got =%v", p.Peek())
@@ -190,9 +559,42 @@ func (p *Parser) Parse() (Operation, error) {
 
 	}
 
+	if p.op.Command == Update && len(p.op.Payload.Data) == 0 {
+		return *p.op, fmt.Errorf("Parsing error: The UPDATE command requires a payload or a SET clause")
+	}
+
+	if p.op.Command == Insert && p.op.Payload.Data == nil {
+		return *p.op, fmt.Errorf("Parsing error: The INSERT command requires a payload")
+	}
+
 	return *p.op, nil
 }
 
+// isCount reports whether `tok` is a whole number without a
+// sign, such as the number of documents after FIRST
+func isCount(tok Token) bool {
+	if tok.Type != NumberValue {
+		return false
+	}
+
+	for i := 0; i < len(tok.Value); i++ {
+		if !isNumeric(tok.Value[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// setData sets a value in the payload of the operation
+func (p *Parser) setData(name string, v interface{}) {
+	if p.op.Payload.Data == nil {
+		p.op.Payload.Data = make(map[string]interface{})
+	}
+
+	p.op.Payload.Data[name] = v
+}
+
 func (p *Parser) CurrentToken() Token {
 	if p.index >= len(p.tokens) {
 		return EOFToken
@@ -1,10 +1,13 @@
 package queries
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/namvu9/keylime/src/errors"
 	"github.com/namvu9/keylime/src/types"
 )
 
@@ -35,25 +38,74 @@ func TestParse(t *testing.T) {
 			Data: map[string]interface{}{
 				"age": "lol",
 				"obj": map[string]interface{}{
-					"number": []interface{}{1.0, 2.0, 3.0},
+					"number": []interface{}{json.Number("1"), json.Number("2"), json.Number("3")},
 				},
 			},
 		},
 		{
 			tokens: []Token{
+				Keyword("GET"),
+				Identifier("name"),
+				Keyword("AS"),
+				Identifier("n"),
+				Delimiter(COMMA),
+				Identifier("address"),
+				Delimiter(PERIOD),
+				Identifier("city"),
+				Delimiter(COMMA),
+				Identifier("tags"),
+				Delimiter(LBRACKET),
+				Number("0"),
+				Delimiter(RBRACKET),
+				Delimiter(COMMA),
+				Identifier("friends"),
+				Delimiter(LBRACKET),
+				Delimiter(STAR),
+				Delimiter(RBRACKET),
+				Delimiter(PERIOD),
+				Identifier("name"),
 				Keyword("FROM"),
 				Identifier("a"),
-				Keyword("GET"),
-				Identifier("a b c d e[0] f[0:]"),
 				Keyword("IN"),
 				Identifier("test"),
+				Keyword("STRICT"),
 				EOFToken,
 			},
 			Collection: "test",
 			Command:    Get,
 			Arguments: map[string]string{
-				"key":       "a",
-				"selectors": "a b c d e[0] f[0:]",
+				"key":    "a",
+				"strict": "true",
+			},
+			Data: map[string]interface{}{
+				"projection": []types.Projection{
+					{
+						Path: types.FieldPath{{Kind: types.FieldStep, Name: "name"}},
+						Name: "n",
+					},
+					{
+						Path: types.FieldPath{
+							{Kind: types.FieldStep, Name: "address"},
+							{Kind: types.FieldStep, Name: "city"},
+						},
+						Name: "address.city",
+					},
+					{
+						Path: types.FieldPath{
+							{Kind: types.FieldStep, Name: "tags"},
+							{Kind: types.IndexStep, Index: 0},
+						},
+						Name: "tags[0]",
+					},
+					{
+						Path: types.FieldPath{
+							{Kind: types.FieldStep, Name: "friends"},
+							{Kind: types.WildcardStep},
+							{Kind: types.FieldStep, Name: "name"},
+						},
+						Name: "friends[*].name",
+					},
+				},
 			},
 		},
 		{
@@ -71,6 +123,342 @@ func TestParse(t *testing.T) {
 				"key": "a",
 			},
 		},
+		{
+			tokens: []Token{
+				Keyword("WITH"),
+				String(`{"age": 2}`),
+				Keyword("UPSERT"),
+				Identifier("a"),
+				Keyword("IN"),
+				Identifier("users"),
+				EOFToken,
+			},
+			Collection: "users",
+			Command:    Upsert,
+			Arguments: map[string]string{
+				"key": "a",
+			},
+			Data: map[string]interface{}{
+				"age": json.Number("2"),
+			},
+		},
+		{
+			tokens: []Token{
+				Keyword("DELETE"),
+				Identifier("a"),
+				Keyword("IN"),
+				Identifier("users"),
+				Keyword("IF"),
+				Keyword("VERSION"),
+				Delimiter(EQUALS),
+				String("abc="),
+				EOFToken,
+			},
+			Collection: "users",
+			Command:    Delete,
+			Arguments: map[string]string{
+				"key":     "a",
+				"version": "abc=",
+			},
+		},
+		{
+			tokens: []Token{
+				Keyword("GET"),
+				Identifier("a"),
+				Keyword("IN"),
+				Identifier("users"),
+				Keyword("AT"),
+				Keyword("REVISION"),
+				Number("3"),
+				EOFToken,
+			},
+			Collection: "users",
+			Command:    Get,
+			Arguments: map[string]string{
+				"key":      "a",
+				"revision": "3",
+			},
+		},
+		{
+			tokens: []Token{
+				Keyword("HISTORY"),
+				Identifier("a"),
+				Keyword("IN"),
+				Identifier("users"),
+				EOFToken,
+			},
+			Collection: "users",
+			Command:    History,
+			Arguments: map[string]string{
+				"key": "a",
+			},
+		},
+		{
+			tokens: []Token{
+				Keyword("WITH"),
+				Keyword("HISTORY"),
+				Number("10"),
+				Keyword("CREATE"),
+				Identifier("users"),
+				EOFToken,
+			},
+			Collection: "users",
+			Command:    Create,
+			Arguments: map[string]string{
+				"history": "10",
+			},
+		},
+		{
+			tokens: []Token{
+				Keyword("WITH"),
+				String(`{"user": "a"}`),
+				Keyword("SET"),
+				Identifier("s1"),
+				Keyword("IN"),
+				Identifier("sessions"),
+				Keyword("TTL"),
+				Number("3600"),
+				EOFToken,
+			},
+			Collection: "sessions",
+			Command:    Set,
+			Arguments: map[string]string{
+				"key": "s1",
+				"ttl": "3600",
+			},
+			Data: map[string]interface{}{
+				"user": "a",
+			},
+		},
+		{
+			tokens: []Token{
+				Keyword("LAST"),
+				Number("10"),
+				Keyword("IN"),
+				Identifier("users"),
+				Keyword("AFTER"),
+				Identifier("MQ5GEMJUGI3TKNBUHAZDCLJQHIYQ"),
+				EOFToken,
+			},
+			Collection: "users",
+			Command:    Last,
+			Arguments: map[string]string{
+				"n":     "10",
+				"after": "MQ5GEMJUGI3TKNBUHAZDCLJQHIYQ",
+			},
+		},
+		{
+			tokens: []Token{
+				Keyword("FIRST"),
+				Number("10"),
+				Keyword("IN"),
+				Identifier("users"),
+				Keyword("ORDER"),
+				Keyword("BY"),
+				Keyword("KEY"),
+				Keyword("OFFSET"),
+				Number("20"),
+				EOFToken,
+			},
+			Collection: "users",
+			Command:    First,
+			Arguments: map[string]string{
+				"n":     "10",
+				"order": "key",
+				"skip":  "20",
+			},
+		},
+		{
+			tokens: []Token{
+				Keyword("COUNT"),
+				Keyword("IN"),
+				Identifier("users"),
+				Keyword("WHERE"),
+				Identifier("alive"),
+				Delimiter(EQUALS),
+				Boolean("true"),
+				Keyword("AND"),
+				Identifier("address"),
+				Delimiter(PERIOD),
+				Identifier("city"),
+				Delimiter(BANG),
+				Delimiter(EQUALS),
+				String("Oslo"),
+				Delimiter(SEMICOLON),
+				EOFToken,
+			},
+			Collection: "users",
+			Command:    Count,
+			Data: map[string]interface{}{
+				"where": types.Filter{
+					{Path: []string{"alive"}, Op: types.Eq, Value: types.Field{Type: types.Boolean, Value: true}},
+					{Path: []string{"address", "city"}, Op: types.Ne, Value: types.Field{Type: types.String, Value: "Oslo"}},
+				},
+			},
+		},
+		{
+			tokens: []Token{
+				Keyword("SELECT"),
+				Keyword("AVG"),
+				Delimiter(LPAREN),
+				Identifier("age"),
+				Delimiter(RPAREN),
+				Delimiter(COMMA),
+				Keyword("COUNT"),
+				Delimiter(LPAREN),
+				Delimiter(STAR),
+				Delimiter(RPAREN),
+				Keyword("FROM"),
+				Identifier("users"),
+				Keyword("WHERE"),
+				Identifier("age"),
+				Delimiter(GREATER),
+				Delimiter(EQUALS),
+				Number("18"),
+				Keyword("GROUP"),
+				Keyword("BY"),
+				Identifier("country"),
+				Delimiter(SEMICOLON),
+				EOFToken,
+			},
+			Collection: "users",
+			Command:    Select,
+			Data: map[string]interface{}{
+				"aggregates": []types.Aggregate{
+					{Func: types.Avg, Path: []string{"age"}},
+					{Func: types.Count},
+				},
+				"where": types.Filter{
+					{Path: []string{"age"}, Op: types.Ge, Value: types.Field{Type: types.Number, Value: json.Number("18")}},
+				},
+				"groupBy": [][]string{{"country"}},
+			},
+		},
+		{
+			tokens: []Token{
+				Keyword("LAST"),
+				Number("10"),
+				Keyword("IN"),
+				Identifier("users"),
+				Keyword("ORDER"),
+				Keyword("BY"),
+				Identifier("address"),
+				Delimiter(PERIOD),
+				Identifier("city"),
+				Delimiter(COMMA),
+				Identifier("age"),
+				Keyword("DESC"),
+				Delimiter(SEMICOLON),
+				EOFToken,
+			},
+			Collection: "users",
+			Command:    Last,
+			Arguments: map[string]string{
+				"n": "10",
+			},
+			Data: map[string]interface{}{
+				"orderBy": types.OrderBy{
+					{Path: []string{"address", "city"}},
+					{Path: []string{"age"}, Desc: true},
+				},
+			},
+		},
+		{
+			tokens: []Token{
+				Keyword("UPDATE"),
+				Identifier("user1"),
+				Keyword("IN"),
+				Identifier("users"),
+				Keyword("SET"),
+				Identifier("age"),
+				Delimiter(PLUS),
+				Delimiter(EQUALS),
+				Number("1"),
+				Delimiter(COMMA),
+				Identifier("address"),
+				Delimiter(PERIOD),
+				Identifier("city"),
+				Delimiter(EQUALS),
+				String("Bergen"),
+				Delimiter(COMMA),
+				Keyword("UNSET"),
+				Identifier("nickname"),
+				Delimiter(COMMA),
+				Keyword("PUSH"),
+				Identifier("tags"),
+				String("x"),
+				Delimiter(SEMICOLON),
+				EOFToken,
+			},
+			Collection: "users",
+			Command:    Update,
+			Arguments: map[string]string{
+				"key": "user1",
+			},
+			Data: map[string]interface{}{
+				"ops": []types.UpdateOp{
+					{Kind: types.IncOp, Path: []string{"age"}, Value: json.Number("1")},
+					{Kind: types.SetOp, Path: []string{"address", "city"}, Value: "Bergen"},
+					{Kind: types.UnsetOp, Path: []string{"nickname"}},
+					{Kind: types.PushOp, Path: []string{"tags"}, Value: "x"},
+				},
+			},
+		},
+		{
+			tokens: []Token{
+				Keyword("LOAD"),
+				String("./users.jsonl"),
+				Keyword("IN"),
+				Identifier("users"),
+				Delimiter(SEMICOLON),
+				EOFToken,
+			},
+			Collection: "users",
+			Command:    Load,
+			Arguments: map[string]string{
+				"path": "./users.jsonl",
+			},
+		},
+		{
+			tokens: []Token{
+				Keyword("WITH"),
+				String(`{"a": {"age": 1}, "b": {"age": 2}}`),
+				Keyword("MSET"),
+				Keyword("PARTIAL"),
+				Keyword("IN"),
+				Identifier("users"),
+				Delimiter(SEMICOLON),
+				EOFToken,
+			},
+			Collection: "users",
+			Command:    MSet,
+			Arguments: map[string]string{
+				"partial": "true",
+			},
+			Data: map[string]interface{}{
+				"a": map[string]interface{}{"age": json.Number("1")},
+				"b": map[string]interface{}{"age": json.Number("2")},
+			},
+		},
+		{
+			tokens: []Token{
+				Keyword("MGET"),
+				Identifier("a"),
+				Delimiter(COMMA),
+				Identifier("b"),
+				Delimiter(COMMA),
+				Identifier("c"),
+				Keyword("IN"),
+				Identifier("users"),
+				Delimiter(SEMICOLON),
+				EOFToken,
+			},
+			Collection: "users",
+			Command:    MGet,
+			Arguments: map[string]string{
+				"keys": "a b c",
+			},
+		},
 	} {
 		op, err := parseTokens(test.tokens)
 
@@ -191,3 +579,415 @@ func TestParseSchema(t *testing.T) {
 	}
 
 }
+
+func TestParseSchemaConstraints(t *testing.T) {
+	op, err := Parse(`WITH SCHEMA {
+		name: String(2,10, pattern="^[a-z]+$"),
+		email: String(format="email"),
+		role: Enum("admin","user"),
+		level: Enum(1,2,3),
+		age: Number(0..120, integer),
+		temp: Number(-10.5..)?,
+		id: Number(9007199254740993..),
+		rate: Number(..0.12345678901234567891)
+	} CREATE users`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := op.Payload.Data["schema"].(*types.Schema)
+
+	valid := map[string]interface{}{
+		"name":  "nam",
+		"email": "nam@example.com",
+		"role":  "admin",
+		"level": 2,
+		"age":   30,
+		"temp":  -10.5,
+		"id":    json.Number("9007199254740993"),
+		"rate":  json.Number("0.12345678901234567891"),
+	}
+	if err := s.Validate(types.NewDoc("k").Set(valid)); err != nil {
+		t.Errorf("Want nil error, Got %s", err)
+	}
+
+	for field, value := range map[string]interface{}{
+		"name":  "Nam",
+		"email": "nam",
+		"role":  "root",
+		"level": 4,
+		"age":   30.5,
+		"temp":  -11,
+		// Bounds are compared exactly
+		"id":   json.Number("9007199254740992"),
+		"rate": json.Number("0.123456789012345679"),
+	} {
+		fields := types.CopyObj(valid)
+		fields[field] = value
+
+		err := s.Validate(types.NewDoc("k").Set(fields))
+		ve, ok := err.(types.ValidationError)
+		if !ok || len(ve) != 1 || ve[field] == nil {
+			t.Errorf("%s=%v: Expected %s to be invalid, Got %v", field, value, field, err)
+		}
+	}
+
+	for _, input := range []string{
+		`WITH SCHEMA { a: Number(pattern="x") } CREATE c`,
+		`WITH SCHEMA { a: String(format="phone") } CREATE c`,
+		`WITH SCHEMA { a: String(pattern="[") } CREATE c`,
+		`WITH SCHEMA { a: Number(10..1) } CREATE c`,
+		`WITH SCHEMA { a: Enum("a", 1) } CREATE c`,
+		`WITH SCHEMA { a: Number(1) } CREATE c`,
+	} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("%s: Want error, Got nil", input)
+		}
+	}
+}
+
+func TestParseTimestampAndBytes(t *testing.T) {
+	op, err := Parse(`WITH SCHEMA { born: Timestamp, avatar: Bytes? } CREATE people`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := op.Payload.Data["schema"].(*types.Schema)
+	doc := types.NewDoc("k").Set(map[string]interface{}{
+		"born":   "1990-01-02T03:04:05Z",
+		"avatar": "aGk=",
+	})
+
+	if err := s.Validate(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Fields["born"].Type != types.Timestamp || doc.Fields["avatar"].Type != types.Bytes {
+		t.Errorf("Expected fields to be converted, Got %v", doc.Fields)
+	}
+}
+
+func TestParseDataPrecision(t *testing.T) {
+	op, err := Parse(`WITH '{"id": 9007199254740993, "price": {"amount": 19.99}}' SET a IN c`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := op.Payload.Data["id"]; got != json.Number("9007199254740993") {
+		t.Errorf("Want id 9007199254740993, Got %v", got)
+	}
+
+	price := op.Payload.Data["price"].(map[string]interface{})
+	if got := price["amount"]; got != json.Number("19.99") {
+		t.Errorf("Want amount 19.99, Got %v", got)
+	}
+}
+
+func TestParseArraySchema(t *testing.T) {
+	op, err := Parse(`WITH SCHEMA {
+		items: []{ price: Number }(1,10),
+		tags: []String(unique)?,
+		scores: [Number(0..1)],
+		matrix: [[Number]](2,2)
+	} CREATE orders`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := op.Payload.Data["schema"].(*types.Schema)
+
+	err = s.Validate(types.NewDoc("k").Set(map[string]interface{}{
+		"items":  []interface{}{map[string]interface{}{"price": 1}},
+		"tags":   []interface{}{"a"},
+		"scores": []interface{}{0.5},
+		"matrix": []interface{}{[]interface{}{1}, []interface{}{2, 3}},
+	}))
+	if err != nil {
+		t.Fatalf("Want nil error, Got %s", err)
+	}
+
+	err = s.Validate(types.NewDoc("k").Set(map[string]interface{}{
+		"items":  []interface{}{map[string]interface{}{"price": "x"}},
+		"tags":   []interface{}{"a", "a"},
+		"scores": []interface{}{2},
+		"matrix": []interface{}{[]interface{}{"x"}},
+	}))
+
+	ve, ok := err.(types.ValidationError)
+	if !ok || len(ve) != 4 {
+		t.Errorf("Expected every field to be invalid, Got %v", err)
+	}
+}
+
+func TestParseRef(t *testing.T) {
+	op, err := Parse(`WITH SCHEMA {
+		user: Ref(users),
+		reviewer: Ref(users, onDelete="set-null")?
+	} CREATE orders`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := op.Payload.Data["schema"].(*types.Schema)
+	want := map[string]types.Reference{
+		"user":     {Collection: "users"},
+		"reviewer": {Collection: "users", OnDelete: types.SetNull},
+	}
+	if got := s.References(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Want %v, Got %v", want, got)
+	}
+
+	if _, err := Parse(`WITH SCHEMA { user: Ref(users, onDelete="set-null") } CREATE orders`); err == nil {
+		t.Errorf("Expected set-null on a required field to be rejected")
+	}
+
+	op, err = Parse(`GET order1 IN orders EXPAND user, reviewer;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := op.Payload.Data["expand"]; !reflect.DeepEqual(got, []string{"user", "reviewer"}) {
+		t.Errorf("Want expand [user reviewer], Got %v", got)
+	}
+}
+
+func TestParseUnique(t *testing.T) {
+	op, err := Parse(`WITH SCHEMA { email: String(format="email") UNIQUE, nick: String UNIQUE? } CREATE users`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := op.Payload.Data["schema"].(*types.Schema)
+	if got := s.UniqueFields(); !reflect.DeepEqual(got, []string{"email", "nick"}) {
+		t.Errorf("Want unique fields [email nick], Got %v", got)
+	}
+
+	for _, schema := range []string{
+		`{ tags: []String UNIQUE }`,
+		`{ nick: String UNIQUE = "anon" }`,
+		`{ address: { city: String UNIQUE } }`,
+	} {
+		if _, err := Parse(`WITH SCHEMA ` + schema + ` CREATE users`); err == nil {
+			t.Errorf("%s: Expected the schema to be rejected", schema)
+		}
+	}
+}
+
+func TestParseComputedFields(t *testing.T) {
+	op, err := Parse(`WITH SCHEMA {
+		first: String,
+		last: String,
+		id: String = uuid(),
+		createdBy: String = $client,
+		updatedAt: Timestamp = now(),
+		fullName = first + " " + last,
+		nick: String = "anon"
+	} CREATE users`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := op.Payload.Data["schema"].(*types.Schema)
+	for _, want := range []string{
+		`id: String = uuid()`,
+		`createdBy: String = $client`,
+		`updatedAt: Timestamp = now()`,
+		`fullName: String = first + " " + last`,
+		`nick: String = anon`,
+	} {
+		if !strings.Contains(s.String(), want) {
+			t.Errorf("Want schema to contain %s, Got %s", want, s)
+		}
+	}
+
+	if _, err := Parse(`WITH SCHEMA { fullName } CREATE users`); err == nil {
+		t.Errorf("Expected a field without a type or expression to be rejected")
+	}
+}
+
+func TestParseInsert(t *testing.T) {
+	op, err := Parse(`INSERT INTO users WITH '{"name": "Ola"}' TTL 60;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if op.Command != Insert || op.Collection != "users" || op.Payload.Data["name"] != "Ola" || op.Arguments["ttl"] != "60" {
+		t.Errorf("Unexpected operation %+v", op)
+	}
+
+	if _, err := Parse(`INSERT INTO users;`); err == nil {
+		t.Errorf("Expected INSERT without a payload to be rejected")
+	}
+
+	op, err = Parse(`CREATE users KEYS uuidv7;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if op.Arguments["keys"] != "uuidv7" {
+		t.Errorf("Want keys uuidv7, Got %v", op.Arguments)
+	}
+}
+
+func TestParseDescribe(t *testing.T) {
+	op, err := Parse(`DESCRIBE users;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if op.Command != Describe || op.Collection != "users" || op.Arguments["format"] != "" {
+		t.Errorf("Unexpected operation %+v", op)
+	}
+
+	op, err = Parse(`DESCRIBE users AS JSON SCHEMA;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if op.Arguments["format"] != "jsonschema" {
+		t.Errorf("Want format jsonschema, Got %v", op.Arguments)
+	}
+
+	op, err = Parse(`CREATE users WITH JSON SCHEMA '{
+		"type": "object",
+		"properties": {"name": {"type": "string", "minLength": 1}},
+		"required": ["name"]
+	}';`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema, ok := op.Payload.Data["schema"].(*types.Schema)
+	if !ok {
+		t.Fatalf("Want a schema, Got %v", op.Payload.Data)
+	}
+
+	if got := schema.Describe(); len(got) != 1 || got[0].Name != "name" || !got[0].Required || *got[0].MinLength != 1 {
+		t.Errorf("Unexpected schema %+v", got)
+	}
+
+	if _, err := Parse(`CREATE users WITH JSON SCHEMA '{"type": "string"}';`); err == nil {
+		t.Errorf("Expected a JSON Schema that is not an object to be rejected")
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	op, err := Parse(`INFO users AS TEXT;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if op.Command != Info || op.Collection != "users" || op.Arguments["format"] != "text" {
+		t.Errorf("Unexpected operation %+v", op)
+	}
+
+	if _, err := Parse(`INFO users AS JSON;`); err == nil {
+		t.Errorf("Expected INFO AS JSON to be rejected")
+	}
+}
+
+func TestParseCollectionCommands(t *testing.T) {
+	for _, test := range []struct {
+		query string
+		want  Operation
+	}{
+		{"SHOW COLLECTIONS;", Operation{Command: Show, Arguments: map[string]string{}}},
+		{"DROP users;", Operation{Command: Drop, Collection: "users", Arguments: map[string]string{}}},
+		{"RENAME users TO people;", Operation{Command: Rename, Collection: "users", Arguments: map[string]string{"to": "people"}}},
+	} {
+		op, err := Parse(test.query)
+		if err != nil {
+			t.Fatalf("%s: %v", test.query, err)
+		}
+
+		if op.Command != test.want.Command || op.Collection != test.want.Collection || !reflect.DeepEqual(op.Arguments, test.want.Arguments) {
+			t.Errorf("%s: Want %+v, Got %+v", test.query, test.want, op)
+		}
+	}
+
+	for _, query := range []string{"SHOW users;", "DROP;", "RENAME users people;", "RENAME users TO;"} {
+		if _, err := Parse(query); err == nil {
+			t.Errorf("%s: Expected a parsing error", query)
+		}
+	}
+}
+
+func TestParseCreateIfNotExists(t *testing.T) {
+	op, err := Parse(`CREATE IF NOT EXISTS users WITH HISTORY 5;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if op.Command != Create || op.Collection != "users" || op.Arguments["ifNotExists"] != "true" || op.Arguments["history"] != "5" {
+		t.Errorf("Unexpected operation %+v", op)
+	}
+
+	if _, err := Parse(`CREATE IF EXISTS users;`); err == nil {
+		t.Errorf("Expected CREATE IF EXISTS to be rejected")
+	}
+}
+
+func TestParseUpdateNumbers(t *testing.T) {
+	op, err := Parse(`UPDATE a IN items SET price += 0.1, stock -= -2, delta = -1.5e3;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []types.UpdateOp{
+		{Kind: types.IncOp, Path: []string{"price"}, Value: json.Number("0.1")},
+		{Kind: types.IncOp, Path: []string{"stock"}, Value: json.Number("2")},
+		{Kind: types.SetOp, Path: []string{"delta"}, Value: json.Number("-1.5e3")},
+	}
+
+	if got := op.Payload.Data["ops"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Want %v, Got %v", want, got)
+	}
+
+	for _, query := range []string{
+		`UPDATE a IN items SET price = 1 2;`,
+		`UPDATE a IN items SET price += 0 .1;`,
+		`UPDATE a IN items SET name = "x" y;`,
+	} {
+		if _, err := Parse(query); errors.GetKind(err) != errors.EBadRequest {
+			t.Errorf("%s: Want error with code %s, Got %v", query, errors.EBadRequest, err)
+		}
+	}
+
+	for _, query := range []string{`FIRST -5 IN items;`, `GET a IN items SKIP 1.5;`} {
+		if _, err := Parse(query); err == nil {
+			t.Errorf("%s: Expected a parsing error", query)
+		}
+	}
+}
+
+func TestParseWhereNumbers(t *testing.T) {
+	op, err := Parse(`COUNT IN items WHERE price = 0.1 AND delta < -2 AND rate >= -0.5;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	where, _ := op.Payload.Data["where"].(types.Filter)
+	want := types.Filter{
+		{Path: []string{"price"}, Op: types.Eq, Value: types.Field{Type: types.Number, Value: json.Number("0.1")}},
+		{Path: []string{"delta"}, Op: types.Lt, Value: types.Field{Type: types.Number, Value: json.Number("-2")}},
+		{Path: []string{"rate"}, Op: types.Ge, Value: types.Field{Type: types.Number, Value: json.Number("-0.5")}},
+	}
+
+	if !reflect.DeepEqual(where, want) {
+		t.Fatalf("Want %v, Got %v", want, where)
+	}
+
+	for _, test := range []struct {
+		fields map[string]interface{}
+		match  bool
+	}{
+		{map[string]interface{}{"price": json.Number("0.1"), "delta": json.Number("-3"), "rate": json.Number("-0.25")}, true},
+		{map[string]interface{}{"price": json.Number("0"), "delta": json.Number("-3"), "rate": json.Number("-0.25")}, false},
+		{map[string]interface{}{"price": json.Number("0.1"), "delta": json.Number("-2"), "rate": json.Number("-0.25")}, false},
+		{map[string]interface{}{"price": json.Number("0.1"), "delta": json.Number("-3"), "rate": json.Number("-0.75")}, false},
+	} {
+		if got := where.Match(types.NewDoc("a").Set(test.fields)); got != test.match {
+			t.Errorf("%v: Want match=%v, Got %v", test.fields, test.match, got)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package queries
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/namvu9/keylime/src/types"
+)
+
+// parseFieldPath parses a field path such as address.city,
+// tags[0], tags[*] or address.*, and returns it along with
+// its source text
+func parseFieldPath(p *Parser) (types.FieldPath, string, error) {
+	if p.Peek().Type != IdentifierToken {
+		return nil, "", fmt.Errorf("Parsing error: Expected field name, but got %v", p.Peek())
+	}
+
+	var sb strings.Builder
+
+	name := p.Next().Value
+	sb.WriteString(name)
+	path := types.FieldPath{{Kind: types.FieldStep, Name: name}}
+
+	for {
+		switch p.Peek().Value {
+		case PERIOD:
+			p.Next()
+			sb.WriteString(PERIOD)
+
+			switch next := p.Next(); {
+			case next.Value == STAR:
+				path = append(path, types.Step{Kind: types.WildcardStep})
+			case next.Type == IdentifierToken:
+				path = append(path, types.Step{Kind: types.FieldStep, Name: next.Value})
+			default:
+				return nil, "", fmt.Errorf("Parsing error: Expected field name after PERIOD, but got %v", next)
+			}
+
+			sb.WriteString(p.CurrentToken().Value)
+
+		case LBRACKET:
+			p.Next()
+
+			switch next := p.Next(); {
+			case next.Value == STAR:
+				path = append(path, types.Step{Kind: types.WildcardStep})
+			case isCount(next):
+				i, err := strconv.Atoi(next.Value)
+				if err != nil {
+					return nil, "", err
+				}
+				path = append(path, types.Step{Kind: types.IndexStep, Index: i})
+			default:
+				return nil, "", fmt.Errorf("Parsing error: Expected index or STAR after LBRACKET, but got %v", next)
+			}
+
+			if p.Peek().Value != RBRACKET {
+				return nil, "", fmt.Errorf("Parsing error: Expected RBRACKET, but got %v", p.Peek())
+			}
+			p.Next()
+
+			fmt.Fprintf(&sb, "[%s]", p.tokens[p.index-1].Value)
+
+		default:
+			return path, sb.String(), nil
+		}
+	}
+}
+
+// parseProjection parses a comma-separated list of field
+// paths, each optionally followed by AS and an alias
+func parseProjection(p *Parser) ([]types.Projection, error) {
+	var ps []types.Projection
+
+	for {
+		path, name, err := parseFieldPath(p)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.Peek().Value == "AS" {
+			p.Next()
+
+			if p.Peek().Type != IdentifierToken {
+				return nil, fmt.Errorf("Parsing error: Expected Identifier token after AS, but got %v", p.Peek())
+			}
+
+			name = p.Next().Value
+		}
+
+		ps = append(ps, types.Projection{Path: path, Name: name})
+
+		if p.Peek().Value != COMMA {
+			return ps, nil
+		}
+		p.Next()
+	}
+}
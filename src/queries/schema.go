@@ -31,6 +31,10 @@ func parseFieldType(p *Parser, sb *types.SchemaBuilder) (types.Type, error) {
 		return types.Boolean, nil
 	case "String":
 		return types.String, nil
+	case "Timestamp":
+		return types.Timestamp, nil
+	case "Bytes":
+		return types.Bytes, nil
 	case LBRACKET:
 		if p.Peek().Value != RBRACKET {
 			return types.Unknown, fmt.Errorf("Expected RBRACKET after LBRACKET got %s", p.Peek())
@@ -56,11 +60,7 @@ func parseDefaultValue(p *Parser) (interface{}, error) {
 	if tok.Type != StringValue {
 		switch tok.Type {
 		case NumberValue:
-			val, err := strconv.ParseFloat(tok.Value, 64)
-			if err != nil {
-				return nil, err
-			}
-			return val, nil
+			return types.ParseNumber(tok.Value)
 		case BooleanValue:
 			val, err := strconv.ParseBool(tok.Value)
 			if err != nil {
@@ -69,7 +69,7 @@ func parseDefaultValue(p *Parser) (interface{}, error) {
 			return val, nil
 		case ArrayValue:
 			var v []interface{}
-			err := json.Unmarshal([]byte(tok.Value), &v)
+			err := decodeJSON(tok.Value, &v)
 			if err != nil {
 				return nil, err
 			}
@@ -77,7 +77,7 @@ func parseDefaultValue(p *Parser) (interface{}, error) {
 			return v, nil
 		case ObjectValue, MapValue:
 			var v map[string]interface{}
-			err := json.Unmarshal([]byte(tok.Value), &v)
+			err := decodeJSON(tok.Value, &v)
 			if err != nil {
 				return nil, err
 			}
@@ -88,13 +88,85 @@ func parseDefaultValue(p *Parser) (interface{}, error) {
 	return tok.Value, nil
 }
 
+// parseExpr parses the expression of a computed field: one
+// or more terms joined by PLUS, where a term is a literal,
+// a function call such as uuid(), a variable such as
+// $client or the name of another field
+func parseExpr(p *Parser) (types.Expr, error) {
+	var terms []types.Expr
+
+	for {
+		term, err := parseTerm(p)
+		if err != nil {
+			return types.Expr{}, err
+		}
+		terms = append(terms, term)
+
+		if p.CurrentToken().Value != PLUS {
+			break
+		}
+		p.Next()
+	}
+
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+
+	return types.Plus(terms...), nil
+}
+
+func parseTerm(p *Parser) (types.Expr, error) {
+	tok := p.CurrentToken()
+
+	switch {
+	case tok.Value == DOLLAR:
+		p.Next()
+		if p.CurrentToken().Type != IdentifierToken {
+			return types.Expr{}, fmt.Errorf("Schema syntax error: Expected variable name after $, got %v", p.CurrentToken())
+		}
+
+		name := p.CurrentToken().Value
+		p.Next()
+		return types.Var(name), nil
+
+	case tok.Type == IdentifierToken && p.Peek().Value == LPAREN:
+		p.Next()
+		if p.Next().Value != RPAREN {
+			return types.Expr{}, fmt.Errorf("Schema syntax error: Expected RPAREN after %s(, got %v", tok.Value, p.CurrentToken())
+		}
+
+		p.Next()
+		return types.Call(tok.Value), nil
+
+	case tok.Type == IdentifierToken:
+		p.Next()
+		return types.FieldRef(tok.Value), nil
+
+	case tok.Type == NumberValue:
+		n, err := parseNumberLiteral(p)
+		if err != nil {
+			return types.Expr{}, err
+		}
+		return types.Lit(n), nil
+
+	case tok.IsValueType():
+		v, err := parseDefaultValue(p)
+		if err != nil {
+			return types.Expr{}, err
+		}
+		return types.Lit(v), nil
+	}
+
+	return types.Expr{}, fmt.Errorf("Schema syntax error: Unexpected %v in expression", tok)
+}
+
 func parseRange(p *Parser) (*types.SchemaFieldOption, error) {
 	min := -1
 	max := -1
 
 	p.Next()
 
-	if p.CurrentToken().Type == NumberValue {
+	if isCount(p.CurrentToken()) {
 		n, err := strconv.ParseInt(p.CurrentToken().Value, 0, 0)
 		if err != nil {
 			return nil, err
@@ -104,7 +176,7 @@ func parseRange(p *Parser) (*types.SchemaFieldOption, error) {
 	}
 	if p.CurrentToken().Value == COMMA {
 		p.Next()
-		if p.CurrentToken().Type == NumberValue {
+		if isCount(p.CurrentToken()) {
 			n, err := strconv.ParseInt(p.CurrentToken().Value, 0, 0)
 			if err != nil {
 				return nil, err
@@ -114,8 +186,8 @@ func parseRange(p *Parser) (*types.SchemaFieldOption, error) {
 		}
 	}
 
-	if max <= min && max > -1 {
-		return nil, fmt.Errorf("Range error: Max value must be greater than min value")
+	if max < min && max > -1 {
+		return nil, fmt.Errorf("Range error: Max value must not be less than min value")
 	}
 
 	if min > -1 && max > -1 {
@@ -136,34 +208,254 @@ func parseRange(p *Parser) (*types.SchemaFieldOption, error) {
 	return nil, nil
 }
 
-func parseSchema(p *Parser) (*types.Schema, error) {
-	sb := types.NewSchemaBuilder()
+// parseConstraints parses the constraints of a field within
+// parentheses: a length range for Strings and Arrays, such
+// as (5,16), a value range for Numbers, such as (0..120),
+// `integer`, `unique` and named constraints such as
+// pattern="^[a-z]+$" or format="email"
+func parseConstraints(p *Parser, kind types.Type) ([]types.SchemaFieldOption, error) {
+	var opts []types.SchemaFieldOption
 
-	if p.CurrentToken().Value != LBRACE {
-		return nil, fmt.Errorf("Schema syntax error: Could not find starting LBRACE (Got %v)", p.CurrentToken())
+	if kind.Is(types.Number) {
+		p.Next()
+	} else {
+		rangeOpt, err := parseRange(p)
+		if err != nil {
+			return nil, err
+		}
+
+		if rangeOpt != nil {
+			opts = append(opts, *rangeOpt)
+		}
 	}
 
-	p.Next()
+	for p.CurrentToken().Value != RPAREN {
+		if p.CurrentToken().Value == COMMA {
+			p.Next()
+			continue
+		}
 
-	for p.CurrentToken().Value != RBRACE {
+		opt, err := parseConstraint(p, kind)
+		if err != nil {
+			return nil, err
+		}
 
-		var schemaOptions []types.SchemaFieldOption
+		opts = append(opts, opt...)
+	}
 
-		name, err := parseFieldName(p, sb)
+	return opts, nil
+}
+
+func parseConstraint(p *Parser, kind types.Type) ([]types.SchemaFieldOption, error) {
+	tok := p.CurrentToken()
+
+	switch {
+	case tok.Type == IdentifierToken && tok.Value == "integer":
+		p.Next()
+		return []types.SchemaFieldOption{types.Integer}, nil
+
+	case tok.Type == IdentifierToken && tok.Value == "unique":
+		p.Next()
+		return []types.SchemaFieldOption{types.UniqueItems}, nil
+
+	case tok.Type == IdentifierToken:
+		if p.Peek().Value != EQUALS {
+			return nil, fmt.Errorf("Schema syntax error: Expected EQUALS after %s, got %v", tok.Value, p.Peek())
+		}
+		p.Next()
+		p.Next()
+
+		if p.CurrentToken().Type != StringValue {
+			return nil, fmt.Errorf("Schema syntax error: Expected String value for %s, got %v", tok.Value, p.CurrentToken())
+		}
+		value := p.CurrentToken().Value
+		p.Next()
+
+		switch tok.Value {
+		case "pattern":
+			return []types.SchemaFieldOption{types.WithPattern(value)}, nil
+		case "format":
+			return []types.SchemaFieldOption{types.WithFormat(value)}, nil
+		default:
+			return nil, fmt.Errorf("Schema syntax error: Unknown constraint %s", tok.Value)
+		}
+
+	case kind.Is(types.Number) && (tok.Value == PERIOD || tok.Type == NumberValue):
+		return parseValueRange(p)
+	}
+
+	return nil, fmt.Errorf("Schema syntax error: Unexpected %v in constraints", tok)
+}
+
+// parseValueRange parses a range of the form min..max, where
+// either bound may be left out
+func parseValueRange(p *Parser) ([]types.SchemaFieldOption, error) {
+	var opts []types.SchemaFieldOption
+
+	if p.CurrentToken().Value != PERIOD {
+		min, err := parseNumberLiteral(p)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, types.WithMinimum(min))
+	}
+
+	if p.CurrentToken().Value != PERIOD || p.Peek().Value != PERIOD {
+		return nil, fmt.Errorf("Schema syntax error: Expected .. in range, got %v", p.CurrentToken())
+	}
+	p.Next()
+	p.Next()
+
+	if p.CurrentToken().Type == NumberValue {
+		max, err := parseNumberLiteral(p)
 		if err != nil {
 			return nil, err
 		}
+		opts = append(opts, types.WithMaximum(max))
+	}
+
+	return opts, nil
+}
+
+// parseNumberLiteral parses a number, e.g. -1.5
+func parseNumberLiteral(p *Parser) (json.Number, error) {
+	tok := p.CurrentToken()
+	if tok.Type != NumberValue {
+		return "", fmt.Errorf("Schema syntax error: Expected Number, got %v", tok)
+	}
+	p.Next()
+
+	return types.ParseNumber(tok.Value)
+}
+
+// parseEnum parses a list of values, Enum("a","b"), and
+// returns the type of the values along with the option that
+// constrains a field to them
+func parseEnum(p *Parser) (types.Type, types.SchemaFieldOption, error) {
+	p.Next()
+	if p.CurrentToken().Value != LPAREN {
+		return types.Unknown, nil, fmt.Errorf("Schema syntax error: Expected LPAREN after Enum, got %v", p.CurrentToken())
+	}
+	p.Next()
+
+	var values []interface{}
+	for p.CurrentToken().Value != RPAREN {
+		switch tok := p.CurrentToken(); {
+		case tok.Value == COMMA && len(values) > 0:
+			p.Next()
+		case tok.Type == NumberValue:
+			n, err := parseNumberLiteral(p)
+			if err != nil {
+				return types.Unknown, nil, err
+			}
+			values = append(values, n)
+		default:
+			f, err := parseValue(tok)
+			if err != nil {
+				return types.Unknown, nil, err
+			}
+			values = append(values, f.Value)
+			p.Next()
+		}
+	}
+
+	if len(values) == 0 {
+		return types.Unknown, nil, fmt.Errorf("Schema syntax error: Enum must have at least one value")
+	}
+
+	return types.GetDataType(values[0]), types.WithEnum(values...), nil
+}
+
+// parseRef parses a reference to another collection,
+// Ref(users) or Ref(users, onDelete="cascade")
+func parseRef(p *Parser) (types.SchemaFieldOption, error) {
+	p.Next()
+	if p.CurrentToken().Value != LPAREN {
+		return nil, fmt.Errorf("Schema syntax error: Expected LPAREN after Ref, got %v", p.CurrentToken())
+	}
+	p.Next()
 
+	if p.CurrentToken().Type != IdentifierToken {
+		return nil, fmt.Errorf("Schema syntax error: Expected collection name in Ref, got %v", p.CurrentToken())
+	}
+	collection := p.CurrentToken().Value
+	p.Next()
+
+	var policy types.RefPolicy
+	if p.CurrentToken().Value == COMMA {
 		p.Next()
-		if p.CurrentToken().Value != COLON {
-			return nil, fmt.Errorf("Expected COLON got %v", p.CurrentToken())
+
+		if p.CurrentToken().Value != "onDelete" || p.Peek().Value != EQUALS {
+			return nil, fmt.Errorf("Schema syntax error: Expected onDelete=<policy> in Ref, got %v", p.CurrentToken())
 		}
+		p.Next()
+		p.Next()
 
+		if p.CurrentToken().Type != StringValue {
+			return nil, fmt.Errorf("Schema syntax error: Expected String value for onDelete, got %v", p.CurrentToken())
+		}
+		policy = types.RefPolicy(p.CurrentToken().Value)
 		p.Next()
+	}
+
+	if p.CurrentToken().Value != RPAREN {
+		return nil, fmt.Errorf("Schema syntax error: Expected RPAREN after Ref, got %v", p.CurrentToken())
+	}
+
+	return types.WithRef(collection, policy), nil
+}
 
-		kind, err := parseFieldType(p, sb)
+// parseFieldSpec parses the type of a field along with its
+// constraints, e.g. String(1,10), Ref(users), []Number, []{...},
+// [Number(0..1)] or [[Number]](1,5). It returns with the
+// token that follows the specification as the current token.
+func parseFieldSpec(p *Parser, sb *types.SchemaBuilder) (types.Type, []types.SchemaFieldOption, error) {
+	var (
+		kind types.Type
+		opts []types.SchemaFieldOption
+		err  error
+	)
+
+	switch {
+	case p.CurrentToken().Value == "Enum":
+		var enumOpt types.SchemaFieldOption
+		kind, enumOpt, err = parseEnum(p)
 		if err != nil {
-			return nil, err
+			return "", nil, err
+		}
+
+		p.Next()
+		return kind, []types.SchemaFieldOption{enumOpt}, nil
+
+	case p.CurrentToken().Value == "Ref":
+		refOpt, err := parseRef(p)
+		if err != nil {
+			return "", nil, err
+		}
+
+		p.Next()
+		return types.String, []types.SchemaFieldOption{refOpt}, nil
+
+	case p.CurrentToken().Value == LBRACKET && p.Peek().Value != RBRACKET:
+		p.Next()
+
+		elemKind, elemOpts, err := parseFieldSpec(p, sb)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if p.CurrentToken().Value != RBRACKET {
+			return "", nil, fmt.Errorf("Schema syntax error: Expected RBRACKET after element type, got %v", p.CurrentToken())
+		}
+		p.Next()
+
+		kind = types.Array
+		opts = append(opts, types.WithElements(elemKind, elemOpts...))
+
+	default:
+		kind, err = parseFieldType(p, sb)
+		if err != nil {
+			return "", nil, err
 		}
 
 		p.Next()
@@ -171,9 +463,9 @@ func parseSchema(p *Parser) (*types.Schema, error) {
 		if kind.Is(types.Object) {
 			s, err := parseSchema(p)
 			if err != nil {
-				return nil, fmt.Errorf("%v", err)
+				return "", nil, fmt.Errorf("%v", err)
 			}
-			schemaOptions = append(schemaOptions, types.WithSchema(s))
+			opts = append(opts, types.WithSchema(s))
 			p.Next()
 		}
 
@@ -181,38 +473,76 @@ func parseSchema(p *Parser) (*types.Schema, error) {
 			if p.CurrentToken().Value == LBRACE {
 				s, err := parseSchema(p)
 				if err != nil {
-					return nil, err
+					return "", nil, err
 				}
 
-				schemaOptions = append(schemaOptions, types.WithElementType(types.Object))
-				schemaOptions = append(schemaOptions, types.WithSchema(s))
-			}
-			if p.CurrentToken().IsDataType() {
-				schemaOptions = append(schemaOptions, types.WithElementType(types.Type(p.CurrentToken().Value)))
+				opts = append(opts, types.WithElementType(types.Object))
+				opts = append(opts, types.WithSchema(s))
+				p.Next()
+			} else if p.CurrentToken().IsDataType() {
+				opts = append(opts, types.WithElementType(types.Type(p.CurrentToken().Value)))
 				p.Next()
 			}
+		}
+	}
 
-			if p.CurrentToken().Value == LPAREN {
-				rangeOpt, err := parseRange(p)
-				if err != nil {
-					return nil, err
-				}
-
-				schemaOptions = append(schemaOptions, *rangeOpt)
-				p.Next()
+	if kind.Is(types.Array) || kind.Is(types.String) || kind.Is(types.Number) {
+		if p.CurrentToken().Value == LPAREN {
+			constraints, err := parseConstraints(p, kind)
+			if err != nil {
+				return "", nil, err
 			}
+
+			opts = append(opts, constraints...)
+			p.Next()
 		}
+	}
 
-		if kind == StringValue {
-			if p.CurrentToken().Value == LPAREN {
-				rangeOpt, err := parseRange(p)
-				if err != nil {
-					return nil, err
-				}
+	return kind, opts, nil
+}
 
-				schemaOptions = append(schemaOptions, *rangeOpt)
-				p.Next()
+func parseSchema(p *Parser) (*types.Schema, error) {
+	sb := types.NewSchemaBuilder()
+
+	if p.CurrentToken().Value != LBRACE {
+		return nil, fmt.Errorf("Schema syntax error: Could not find starting LBRACE (Got %v)", p.CurrentToken())
+	}
+
+	p.Next()
+
+	for p.CurrentToken().Value != RBRACE {
+
+		var schemaOptions []types.SchemaFieldOption
+
+		name, err := parseFieldName(p, sb)
+		if err != nil {
+			return nil, err
+		}
+
+		p.Next()
+
+		// The type of a computed field may be left out, e.g.
+		// fullName = first + " " + last
+		var kind types.Type = types.Unknown
+
+		if p.CurrentToken().Value != EQUALS {
+			if p.CurrentToken().Value != COLON {
+				return nil, fmt.Errorf("Expected COLON got %v", p.CurrentToken())
+			}
+
+			p.Next()
+
+			var specOptions []types.SchemaFieldOption
+			kind, specOptions, err = parseFieldSpec(p, sb)
+			if err != nil {
+				return nil, err
 			}
+			schemaOptions = append(schemaOptions, specOptions...)
+		}
+
+		if p.CurrentToken().Value == "UNIQUE" {
+			schemaOptions = append(schemaOptions, types.Unique)
+			p.Next()
 		}
 
 		if p.CurrentToken().Value == QUESTIONMARK {
@@ -222,12 +552,24 @@ func parseSchema(p *Parser) (*types.Schema, error) {
 
 		if p.CurrentToken().Value == EQUALS {
 			p.Next()
-			defaultValue, err := parseDefaultValue(p)
-			if err != nil {
-				return nil, err
-			}
 
-			schemaOptions = append(schemaOptions, types.WithDefault(defaultValue))
+			if p.CurrentToken().IsValueType() && p.Peek().Value != PLUS {
+				defaultValue, err := parseDefaultValue(p)
+				if err != nil {
+					return nil, err
+				}
+
+				schemaOptions = append(schemaOptions, types.WithDefault(defaultValue))
+			} else {
+				expr, err := parseExpr(p)
+				if err != nil {
+					return nil, err
+				}
+
+				schemaOptions = append(schemaOptions, types.WithComputed(expr))
+			}
+		} else if kind == types.Unknown {
+			return nil, fmt.Errorf("Schema syntax error: Expected expression for computed field %s, got %v", name, p.CurrentToken())
 		}
 
 		sb.AddField(name, kind, schemaOptions...)
@@ -78,7 +78,7 @@ func (t Token) IsDataType() bool {
 
 	switch t.Value {
 	// Use c onstants
-	case "String", "Boolean", "Number", "Array", "Object", "Map":
+	case "String", "Boolean", "Number", "Array", "Object", "Map", "Timestamp", "Bytes":
 		return true
 	default:
 		return false
@@ -108,6 +108,13 @@ const (
 	PERIOD       = "."
 	COMMA        = ","
 	EQUALS       = "="
+	STAR         = "*"
+	LESS         = "<"
+	GREATER      = ">"
+	BANG         = "!"
+	PLUS         = "+"
+	MINUS        = "-"
+	DOLLAR       = "$"
 )
 
 type Token struct {
@@ -133,6 +140,13 @@ var delimiters = map[byte]Token{
 	',': Delimiter(COMMA),
 	'.': Delimiter(PERIOD),
 	'=': Delimiter(EQUALS),
+	'*': Delimiter(STAR),
+	'<': Delimiter(LESS),
+	'>': Delimiter(GREATER),
+	'!': Delimiter(BANG),
+	'+': Delimiter(PLUS),
+	'-': Delimiter(MINUS),
+	'$': Delimiter(DOLLAR),
 }
 
 type tokenizer struct {
@@ -168,25 +182,70 @@ func parseLetters(t *tokenizer) {
 	}
 }
 
+// parseNumber reads a number with an optional sign, fraction
+// and exponent, e.g. -1.5e3. The value of the token is a
+// JSON number; a leading plus sign is therefore dropped.
 func parseNumber(t *tokenizer) {
-	var sb strings.Builder
-	l := t.s[t.i]
+	start := t.i
+	if t.s[t.i] == '+' || t.s[t.i] == '-' {
+		t.i++
+	}
+	t.skipDigits()
 
-	for isNumeric(l) {
-		sb.WriteByte(l)
+	// A period that is not followed by a digit, as in the
+	// range 1..5, is a delimiter
+	if t.i < len(t.s) && t.s[t.i] == '.' && t.isDigitAt(t.i+1) {
 		t.i++
+		t.skipDigits()
+	}
 
-		if t.i >= len(t.s) {
-			break
+	if t.i < len(t.s) && (t.s[t.i] == 'e' || t.s[t.i] == 'E') {
+		j := t.i + 1
+		if j < len(t.s) && (t.s[j] == '+' || t.s[j] == '-') {
+			j++
+		}
+
+		if t.isDigitAt(j) {
+			t.i = j
+			t.skipDigits()
 		}
-		l = t.s[t.i]
 	}
 
-	word := sb.String()
-	t.tokens = append(t.tokens, Token{
-		Type:  NumberValue,
-		Value: word,
-	})
+	t.tokens = append(t.tokens, Number(strings.TrimPrefix(t.s[start:t.i], PLUS)))
+}
+
+func (t *tokenizer) skipDigits() {
+	for t.isDigitAt(t.i) {
+		t.i++
+	}
+}
+
+func (t *tokenizer) isDigitAt(i int) bool {
+	return i < len(t.s) && isNumeric(t.s[i])
+}
+
+// isSign reports whether the character at the current
+// position is the sign of a number, rather than an
+// operator: a plus or minus followed by a digit that does
+// not follow a value, field name or closing delimiter, such
+// as the minus in `a = -1` but not in `a-1`.
+func (t *tokenizer) isSign() bool {
+	if c := t.s[t.i]; (c != '+' && c != '-') || !t.isDigitAt(t.i+1) {
+		return false
+	}
+
+	if len(t.tokens) == 0 {
+		return true
+	}
+
+	switch prev := t.tokens[len(t.tokens)-1]; {
+	case prev.IsValueType(), prev.Type == IdentifierToken:
+		return false
+	case prev.Value == RPAREN, prev.Value == RBRACKET, prev.Value == RBRACE:
+		return false
+	default:
+		return true
+	}
 }
 
 func parseString(t *tokenizer) {
@@ -234,7 +293,7 @@ func (t *tokenizer) tokenize() []Token {
 		switch {
 		case isLetter(c):
 			parseLetters(t)
-		case isNumeric(c):
+		case isNumeric(c), t.isSign():
 			parseNumber(t)
 		case isDelimiter(c):
 			t.tokens = append(t.tokens, delimiters[c])
@@ -277,38 +336,109 @@ func isNumeric(c byte) bool {
 	return false
 }
 
+// parseData decodes a JSON payload. Numbers are decoded as
+// json.Number to preserve their precision.
 func parseData(tok Token) (map[string]interface{}, error) {
 	d := map[string]interface{}{}
-	err := json.Unmarshal([]byte(tok.Value), &d)
+	err := decodeJSON(tok.Value, &d)
 	return d, err
 }
 
+func decodeJSON(s string, v interface{}) error {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
 var keywords = map[string]bool{
-	"SELECT":  true,
-	"LAST":    true,
-	"FIRST":   true,
-	"SET":     true,
-	"DELETE":  true,
-	"UPDATE":  true,
-	"CREATE":  true,
-	"SCHEMA":  true,
-	"WITH":    true,
-	"IN":      true,
-	"FROM":    true,
-	"String":  true,
-	"Number":  true,
-	"Array":   true,
-	"Object":  true,
-	"Map":     true,
-	"Boolean": true,
+	"SELECT":      true,
+	"LAST":        true,
+	"FIRST":       true,
+	"SET":         true,
+	"UPSERT":      true,
+	"DELETE":      true,
+	"UPDATE":      true,
+	"CREATE":      true,
+	"SCHEMA":      true,
+	"WITH":        true,
+	"IN":          true,
+	"FROM":        true,
+	"LOAD":        true,
+	"MSET":        true,
+	"MGET":        true,
+	"PARTIAL":     true,
+	"IF":          true,
+	"VERSION":     true,
+	"AT":          true,
+	"REVISION":    true,
+	"HISTORY":     true,
+	"TTL":         true,
+	"AFTER":       true,
+	"SKIP":        true,
+	"OFFSET":      true,
+	"ORDER":       true,
+	"BY":          true,
+	"KEY":         true,
+	"COUNT":       true,
+	"SUM":         true,
+	"AVG":         true,
+	"MIN":         true,
+	"MAX":         true,
+	"WHERE":       true,
+	"GROUP":       true,
+	"AND":         true,
+	"ASC":         true,
+	"DESC":        true,
+	"AS":          true,
+	"STRICT":      true,
+	"TYPED":       true,
+	"UNSET":       true,
+	"PUSH":        true,
+	"String":      true,
+	"Number":      true,
+	"Array":       true,
+	"Object":      true,
+	"Map":         true,
+	"Boolean":     true,
+	"Enum":        true,
+	"Timestamp":   true,
+	"Bytes":       true,
+	"Ref":         true,
+	"EXPAND":      true,
+	"UNIQUE":      true,
+	"INSERT":      true,
+	"INTO":        true,
+	"KEYS":        true,
+	"JSON":        true,
+	"DESCRIBE":    true,
+	"TEXT":        true,
+	"SHOW":        true,
+	"COLLECTIONS": true,
+	"DROP":        true,
+	"RENAME":      true,
+	"TO":          true,
+	"NOT":         true,
+	"EXISTS":      true,
 }
 
 var commands = map[string]Command{
-	"GET":    Get,
-	"SET":    Set,
-	"UPDATE": Update,
-	"INFO":   Info,
-	"CREATE": Create,
-	"LAST":   Last,
-	"FIRST":  First,
+	"GET":      Get,
+	"SET":      Set,
+	"UPSERT":   Upsert,
+	"UPDATE":   Update,
+	"INFO":     Info,
+	"CREATE":   Create,
+	"LAST":     Last,
+	"FIRST":    First,
+	"LOAD":     Load,
+	"MSET":     MSet,
+	"MGET":     MGet,
+	"HISTORY":  History,
+	"SELECT":   Select,
+	"COUNT":    Count,
+	"INSERT":   Insert,
+	"DESCRIBE": Describe,
+	"SHOW":     Show,
+	"DROP":     Drop,
+	"RENAME":   Rename,
 }
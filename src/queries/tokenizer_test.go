@@ -133,6 +133,42 @@ func TestTokenizer(t *testing.T) {
 		}
 	})
 
+	t.Run("Numbers", func(t *testing.T) {
+		input := "= 1.5, -2, +3, [-0.25e-3] a-1 1..5"
+		tokens := tokenize(input)
+
+		expTokens := []Token{
+			Delimiter(EQUALS),
+			Number("1.5"),
+			Delimiter(COMMA),
+			Number("-2"),
+			Delimiter(COMMA),
+			Number("3"),
+			Delimiter(COMMA),
+			Delimiter(LBRACKET),
+			Number("-0.25e-3"),
+			Delimiter(RBRACKET),
+			Identifier("a"),
+			Delimiter(MINUS),
+			Number("1"),
+			Number("1"),
+			Delimiter(PERIOD),
+			Delimiter(PERIOD),
+			Number("5"),
+			EOFToken,
+		}
+
+		if want, got := len(expTokens), len(tokens); want != got {
+			t.Fatalf("len(tokens) want=%d got=%d", want, got)
+		}
+
+		for i, token := range expTokens {
+			if token != tokens[i] {
+				t.Errorf("Token want %v got %v", token, tokens[i])
+			}
+		}
+	})
+
 	t.Run("Schema", func(t *testing.T) {
 		input := `
 	{
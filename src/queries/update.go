@@ -0,0 +1,135 @@
+package queries
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/namvu9/keylime/src/errors"
+	"github.com/namvu9/keylime/src/types"
+)
+
+// parseUpdateOps parses a comma-separated list of update
+// operators:
+//
+//	path = value
+//	path += value
+//	path -= value
+//	UNSET path
+//	PUSH path value
+//
+// An operator must be followed by a comma, a keyword or the
+// end of the statement. Otherwise, an error with code
+// EBadRequest is returned, rather than the rest of the
+// operator being ignored.
+func parseUpdateOps(p *Parser) ([]types.UpdateOp, error) {
+	var ops []types.UpdateOp
+
+	for {
+		op, err := parseUpdateOp(p)
+		if err != nil {
+			return nil, err
+		}
+
+		ops = append(ops, op)
+
+		switch next := p.Peek(); {
+		case next.Value == COMMA:
+			p.Next()
+		case next.Type == EOF, next.Type == KeywordToken, next.Value == SEMICOLON:
+			return ops, nil
+		default:
+			return nil, errors.Wrap("parseUpdateOps", errors.EBadRequest, fmt.Errorf("Parsing error: Unexpected %v after update operator on %s", next, strings.Join(op.Path, ".")))
+		}
+	}
+}
+
+func parseUpdateOp(p *Parser) (types.UpdateOp, error) {
+	var op types.UpdateOp
+
+	switch p.Peek().Value {
+	case "UNSET":
+		p.Next()
+		op.Kind = types.UnsetOp
+
+		path, err := parsePath(p)
+		if err != nil {
+			return op, err
+		}
+		op.Path = path
+
+		return op, nil
+
+	case "PUSH":
+		p.Next()
+		op.Kind = types.PushOp
+
+		path, err := parsePath(p)
+		if err != nil {
+			return op, err
+		}
+		op.Path = path
+
+		value, err := parseValue(p.Next())
+		if err != nil {
+			return op, err
+		}
+		op.Value = value.Value
+
+		return op, nil
+	}
+
+	path, err := parsePath(p)
+	if err != nil {
+		return op, err
+	}
+	op.Path = path
+
+	negate := false
+	switch p.Peek().Value {
+	case EQUALS:
+		op.Kind = types.SetOp
+	case PLUS, MINUS:
+		if p.Peek().Value == MINUS {
+			negate = true
+		}
+		p.Next()
+
+		if p.Peek().Value != EQUALS {
+			return op, fmt.Errorf("Parsing error: Expected EQUALS after %s, but got %v", p.CurrentToken().Value, p.Peek())
+		}
+		op.Kind = types.IncOp
+	default:
+		return op, fmt.Errorf("Parsing error: Expected update operator, but got %v", p.Peek())
+	}
+	p.Next()
+
+	value, err := parseValue(p.Next())
+	if err != nil {
+		return op, err
+	}
+	op.Value = value.Value
+
+	if op.Kind == types.IncOp {
+		n, ok := op.Value.(json.Number)
+		if !ok {
+			return op, fmt.Errorf("Parsing error: Expected Number to add to %s, but got %v", strings.Join(path, "."), p.CurrentToken())
+		}
+
+		if negate {
+			n = negateNumber(n)
+		}
+		op.Value = n
+	}
+
+	return op, nil
+}
+
+// negateNumber returns `n` with its sign flipped
+func negateNumber(n json.Number) json.Number {
+	if s := string(n); strings.HasPrefix(s, MINUS) {
+		return json.Number(s[1:])
+	}
+
+	return json.Number(MINUS + string(n))
+}
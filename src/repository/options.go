@@ -16,9 +16,13 @@ func WithFactory(r Repository, f Factory) Repository {
 func WithScope(r Repository, name string) Repository {
 	r.scope = path.Join(r.scope, name)
 
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if _, ok := r.items[r.scope]; !ok {
 		r.items[r.scope] = make(map[string]types.Identifier)
 		r.buffer[r.scope] = make(map[string]types.Identifier)
+		r.deleteBuffer[r.scope] = make(map[string]types.Identifier)
 	}
 
 	return r
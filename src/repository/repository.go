@@ -7,6 +7,8 @@ import (
 	"log"
 	"os"
 	"path"
+	"path/filepath"
+	"sync"
 
 	"github.com/namvu9/keylime/src/types"
 )
@@ -55,12 +57,18 @@ type Repository struct {
 	codec   Codec
 	factory Factory
 
+	// mu guards the objects and buffers of every scope, which
+	// are shared by the repositories derived from this one
+	mu           *sync.Mutex
 	items        map[string]map[string]types.Identifier
 	buffer       map[string]map[string]types.Identifier
 	deleteBuffer map[string]map[string]types.Identifier
 }
 
 func (r Repository) Delete(item types.Identifier) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	deletes, ok := r.deleteBuffer[r.scope]
 	if !ok {
 		return fmt.Errorf("scope %s has not been registered", r.scope)
@@ -82,13 +90,147 @@ func (r Repository) Exists(id string) (bool, error) {
 	return true, nil
 }
 
+// Size returns the size on disk of the object with ID `id`,
+// which is 0 if it has not been flushed
+func (r Repository) Size(id string) (int64, error) {
+	info, err := os.Stat(path.Join(r.scope, id))
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return info.Size(), nil
+}
+
+// DiskUsage returns the size on disk of the objects in the
+// repository's scope, including nested scopes
+func (r Repository) DiskUsage() (int64, error) {
+	var total int64
+
+	err := filepath.Walk(r.scope, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			total += info.Size()
+		}
+
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+
+	return total, err
+}
+
+// List returns the IDs of the objects on disk in the
+// repository's scope, excluding nested scopes
+func (r Repository) List() ([]string, error) {
+	entries, err := os.ReadDir(r.scope)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			ids = append(ids, entry.Name())
+		}
+	}
+
+	return ids, nil
+}
+
+// Scopes returns the names of the scopes nested in the
+// repository's scope
+func (r Repository) Scopes() ([]string, error) {
+	entries, err := os.ReadDir(r.scope)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+
+	return names, nil
+}
+
+// Rename moves the repository's scope to `name` within its
+// parent scope and returns a repository for the new scope.
+// Objects loaded from the old scope are forgotten, since
+// they refer to it, and must be loaded again.
+func (r Repository) Rename(name string) (Repository, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.buffer[r.scope]) > 0 || len(r.deleteBuffer[r.scope]) > 0 {
+		return r, fmt.Errorf("Scope %s has changes that have not been flushed", r.scope)
+	}
+
+	to := path.Join(path.Dir(r.scope), name)
+	if _, err := os.Stat(to); err == nil {
+		return r, fmt.Errorf("Scope %s already exists", to)
+	}
+
+	if err := os.Rename(r.scope, to); err != nil {
+		return r, err
+	}
+
+	r.forget()
+	r.scope = to
+	r.items[to] = make(map[string]types.Identifier)
+	r.buffer[to] = make(map[string]types.Identifier)
+	r.deleteBuffer[to] = make(map[string]types.Identifier)
+
+	return r, nil
+}
+
+// DropScope removes the repository's scope, which must be
+// empty, and forgets the objects loaded from it
+func (r Repository) DropScope() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.buffer[r.scope]) > 0 || len(r.deleteBuffer[r.scope]) > 0 {
+		return fmt.Errorf("Scope %s has changes that have not been flushed", r.scope)
+	}
+
+	if err := os.Remove(r.scope); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	r.forget()
+	return nil
+}
+
+func (r Repository) forget() {
+	delete(r.items, r.scope)
+	delete(r.buffer, r.scope)
+	delete(r.deleteBuffer, r.scope)
+}
+
 func (r Repository) Get(id string) (types.Identifier, error) {
+	r.mu.Lock()
 	items, ok := r.items[r.scope]
 	if !ok {
+		r.mu.Unlock()
 		return nil, fmt.Errorf("Scope %s has not been registered", r.scope)
 	}
 
 	n, ok := items[id]
+	r.mu.Unlock()
+
 	if !ok {
 		ok, err := r.Exists(id)
 		if ok {
@@ -110,12 +252,18 @@ func (r Repository) Get(id string) (types.Identifier, error) {
 // Factory.
 func (r Repository) New() types.Identifier {
 	n := r.factory.New()
+
+	r.mu.Lock()
 	r.items[r.scope][n.ID()] = n
+	r.mu.Unlock()
 
 	return n
 }
 
 func (r Repository) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	defer func() {
 		for id, item := range r.buffer[r.scope] {
 			delete(r.buffer[r.scope], id)
@@ -178,6 +326,9 @@ func (r Repository) Save(i types.Identifier) error {
 		return fmt.Errorf("ID must not be empty")
 	}
 
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if _, ok := r.deleteBuffer[r.scope][i.ID()]; !ok {
 		r.buffer[r.scope][i.ID()] = i
 	}
@@ -221,7 +372,14 @@ func (repo *Repository) load(id string) (types.Identifier, error) {
 		return nil, err
 	}
 
-	repo.items[repo.scope][id] = item
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	items, ok := repo.items[repo.scope]
+	if !ok {
+		return nil, fmt.Errorf("Scope %s has not been registered", repo.scope)
+	}
+	items[id] = item
 
 	return item, nil
 }
@@ -248,6 +406,7 @@ func New(scope string, c Codec, s Storage, opts ...Option) Repository {
 	deleteBuffer[scope] = make(map[string]types.Identifier)
 
 	return Repository{
+		mu:           &sync.Mutex{},
 		scope:        scope,
 		items:        items,
 		factory:      NoOpFactory{},
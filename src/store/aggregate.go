@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/namvu9/keylime/src/errors"
+	"github.com/namvu9/keylime/src/types"
+)
+
+// Aggregate evaluates an aggregate query over the live
+// documents in the collection. It returns one row per
+// group, holding the values of the GroupBy fields and of
+// each aggregate, keyed by their string representation.
+// Rows are ordered by the JSON encoding of their GroupBy
+// values.
+func (c *Collection) Aggregate(ctx context.Context, q types.AggregateQuery) ([]map[string]interface{}, error) {
+	if err := c.rlock(); err != nil {
+		return nil, err
+	}
+	defer c.runlock()
+
+	return c.aggregate(ctx, q)
+}
+
+func (c *Collection) aggregate(ctx context.Context, q types.AggregateQuery) ([]map[string]interface{}, error) {
+	var op errors.Op = "(*Collection).Aggregate"
+	log.Printf("Aggregating %d values in %s\n", len(q.Aggregates), c.ID())
+
+	type group struct {
+		row  map[string]interface{}
+		accs []*types.Accumulator
+	}
+
+	groups := make(map[string]*group)
+	newGroup := func(key string, row map[string]interface{}) *group {
+		g := &group{row: row}
+		for _, agg := range q.Aggregates {
+			g.accs = append(g.accs, types.NewAccumulator(agg))
+		}
+
+		groups[key] = g
+		return g
+	}
+
+	// Without GROUP BY, the query produces a single row even
+	// if no documents match
+	if len(q.GroupBy) == 0 {
+		newGroup("[]", make(map[string]interface{}))
+	}
+
+	cur, err := c.scan(ctx, true, types.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	for cur.Next() {
+		doc := c.Schema.WithDefaults(cur.Value())
+		if !q.Where.Match(doc) {
+			continue
+		}
+
+		row, key := groupOf(doc, q.GroupBy)
+		g, ok := groups[key]
+		if !ok {
+			g = newGroup(key, row)
+		}
+
+		for _, acc := range g.accs {
+			if err := acc.Add(doc); err != nil {
+				return nil, errors.Wrap(op, errors.EBadRequest, err)
+			}
+		}
+	}
+
+	if err := cur.Err(); err != nil {
+		return nil, errors.Wrap(op, errors.EInternal, err)
+	}
+
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make([]map[string]interface{}, 0, len(groups))
+	for _, k := range keys {
+		g := groups[k]
+		for i, agg := range q.Aggregates {
+			g.row[agg.String()] = g.accs[i].Value()
+		}
+
+		out = append(out, g.row)
+	}
+
+	return out, nil
+}
+
+// Count returns the number of live documents in the
+// collection that match the filter
+func (c *Collection) Count(ctx context.Context, where types.Filter) (int, error) {
+	if err := c.rlock(); err != nil {
+		return 0, err
+	}
+	defer c.runlock()
+
+	var op errors.Op = "(*Collection).Count"
+
+	rows, err := c.aggregate(ctx, types.AggregateQuery{
+		Aggregates: []types.Aggregate{{Func: types.Count}},
+		Where:      where,
+	})
+	if err != nil {
+		return 0, errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	return rows[0]["COUNT(*)"].(int), nil
+}
+
+// groupOf returns the values of the `groupBy` fields of the
+// document, and a key that identifies the group. Missing
+// fields are grouped under nil.
+func groupOf(doc types.Document, groupBy [][]string) (map[string]interface{}, string) {
+	row := make(map[string]interface{})
+	values := make([]interface{}, len(groupBy))
+
+	for i, path := range groupBy {
+		var v interface{}
+		if f, ok := doc.Get(path...); ok {
+			v = f.Value
+		}
+
+		row[strings.Join(path, ".")] = v
+		values[i] = v
+	}
+
+	key, _ := json.Marshal(values)
+	return row, string(key)
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/namvu9/keylime/src/repository"
@@ -67,6 +67,7 @@ func (bl *Blocklist) insert(ctx context.Context, doc types.Document) (ref string
 		}
 
 		bl.Blocks++
+		bl.Docs++
 		return newHead.ID(), nil
 	}
 
@@ -80,6 +81,47 @@ func (bl *Blocklist) insert(ctx context.Context, doc types.Document) (ref string
 	return string(bl.Head), nil
 }
 
+// insertBatch appends docs to the block list, filling the
+// head block to capacity before allocating a new one. It
+// returns the ID of the block each document was written to.
+func (bl *Blocklist) insertBatch(ctx context.Context, docs []types.Document) ([]string, error) {
+	log.Printf("Block list: inserting %d docs in scope %s\n", len(docs), bl.repo.Scope())
+	head, err := bl.GetBlock(bl.Head)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		if head.Full() {
+			newHead, err := bl.New()
+			if err != nil {
+				return nil, err
+			}
+
+			err = bl.setHeadNode(newHead)
+			if err != nil {
+				return nil, err
+			}
+
+			bl.Blocks++
+			head = newHead
+		}
+
+		head.Docs = append(head.Docs, doc)
+		refs = append(refs, head.ID())
+		bl.Docs++
+	}
+
+	err = head.save()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Block list: done inserting %d docs\n", len(docs))
+	return refs, nil
+}
+
 func (bl *Blocklist) setHeadNode(node *Block) error {
 	headNode, err := bl.GetBlock(bl.Head)
 	if err != nil {
@@ -103,18 +145,20 @@ func (bl *Blocklist) setHeadNode(node *Block) error {
 	return nil
 }
 
-func (bl *Blocklist) remove(ctx context.Context, k string) error {
-	block, _ := bl.GetBlock(bl.Head)
-	for block != nil {
-		for i, record := range block.Docs {
-			if record.Key == k {
-				block.Docs[i].Deleted = true
-				bl.Docs--
-				return block.save()
-			}
-		}
+// tombstone marks the live document with key `k` in block
+// `id` as deleted
+func (bl *Blocklist) tombstone(id ID, k string) error {
+	block, err := bl.GetBlock(id)
+	if err != nil {
+		return err
+	}
 
-		block, _ = bl.GetBlock(block.Next)
+	for i, doc := range block.Docs {
+		if doc.Key == k && !doc.Deleted {
+			block.Docs[i].Deleted = true
+			bl.Docs--
+			return block.save()
+		}
 	}
 
 	return fmt.Errorf("Key not found: %s", k)
@@ -141,70 +185,28 @@ func (bl *Blocklist) create() error {
 	return nil
 }
 
-func (bl *Blocklist) GetN(n int, asc bool) []types.Document {
-	if asc {
-		log.Printf("(*Blocklist).GetN: first %d\n", n)
-	} else {
-		log.Printf("(*Blocklist).GetN: last %d\n", n)
-	}
-	defer log.Println("(*Blocklist).GetN: Done")
-
-	out := []types.Document{}
-
-	var node *Block
-	if asc {
-		node, _ = bl.GetBlock(bl.Tail)
-	} else {
-		node, _ = bl.GetBlock(bl.Head)
-	}
-
-	for node != nil {
-		if asc {
-			for _, r := range node.Docs {
-				if len(out) == n {
-					return out
-				}
-				if !r.Deleted {
-					out = append(out, r)
-				}
-			}
-
-			node, _ = bl.GetBlock(node.Prev)
-		} else {
-			for i := len(node.Docs) - 1; i >= 0; i-- {
-				if len(out) == n {
-					return out
-				}
+// expired returns the keys of the documents in the block
+// list that have expired at time `now` but have not been
+// deleted
+func (bl *Blocklist) expired(now time.Time) ([]string, error) {
+	var keys []string
 
-				r := node.Docs[i]
-
-				if !r.Deleted {
-					out = append(out, r)
-				}
-			}
-
-			node, _ = bl.GetBlock(node.Next)
+	for id := bl.Head; id != ""; {
+		block, err := bl.GetBlock(id)
+		if err != nil {
+			return nil, err
 		}
 
-	}
-
-	return out
-}
-
-func (bl *Blocklist) update(ctx context.Context, r types.Document) error {
-	block, _ := bl.GetBlock(bl.Head)
-	for block != nil {
-		for i, record := range block.Docs {
-			if r.Key == record.Key {
-				block.Docs[i] = r
-				return block.save()
+		for _, doc := range block.Docs {
+			if !doc.Deleted && doc.Expired(now) {
+				keys = append(keys, doc.Key)
 			}
 		}
 
-		block, _ = bl.GetBlock(block.Next)
+		id = block.Next
 	}
 
-	return fmt.Errorf("Key not found: %s", r.Key)
+	return keys, nil
 }
 
 func (bl *Blocklist) New() (*Block, error) {
@@ -217,15 +219,62 @@ func (bl *Blocklist) New() (*Block, error) {
 	return node, nil
 }
 
-func (bl *Blocklist) Info() string {
-	var sb strings.Builder
+// drop queues every block for deletion. The blocks are
+// deleted when the repository is flushed.
+func (bl *Blocklist) drop() error {
+	for id := bl.Head; id != ""; {
+		block, err := bl.GetBlock(id)
+		if err != nil {
+			return err
+		}
+
+		if err := bl.repo.Delete(block); err != nil {
+			return err
+		}
 
-	sb.WriteString("<Block list>\n")
-	sb.WriteString(fmt.Sprintf("Block size: %d\n", bl.BlockSize))
-	sb.WriteString(fmt.Sprintf("Blocks: %d\n", bl.Blocks))
-	sb.WriteString(fmt.Sprintf("Docs: %d", bl.Docs))
+		id = block.Next
+	}
+
+	return nil
+}
+
+// Info walks the block list and returns its stats
+func (bl *Blocklist) Info() (types.BlocklistStats, error) {
+	stats := types.BlocklistStats{
+		BlockSize: bl.BlockSize,
+		Blocks:    bl.Blocks,
+		Docs:      bl.Docs,
+	}
+
+	var held, capacity int
+	for id := bl.Head; id != ""; {
+		block, err := bl.GetBlock(id)
+		if err != nil {
+			return stats, err
+		}
+
+		size, err := bl.repo.Size(string(id))
+		if err != nil {
+			return stats, err
+		}
+		stats.Bytes += size
+
+		for _, doc := range block.Docs {
+			if doc.Deleted {
+				stats.Deleted++
+			}
+		}
+
+		held += len(block.Docs)
+		capacity += block.Capacity
+		id = block.Next
+	}
+
+	if capacity > 0 {
+		stats.FillFactor = float64(held) / float64(capacity)
+	}
 
-	return sb.String()
+	return stats, nil
 }
 
 func newBlocklist(blockSize int, s repository.Repository) Blocklist {
@@ -275,7 +324,7 @@ func (b *Block) Insert(doc types.Document) error {
 // TODO: TEST
 func (b *Block) Update(targetDoc types.Document) error {
 	for i, doc := range b.Docs {
-		if doc.Key == targetDoc.Key {
+		if doc.Key == targetDoc.Key && !doc.Deleted {
 			b.Docs[i] = targetDoc
 			return b.save()
 		}
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/namvu9/keylime/src/repository"
@@ -92,14 +93,16 @@ func TestDeleteBlockList(t *testing.T) {
 	oi.create()
 
 	headNode, err := oi.GetBlock(oi.Head)
+	if err != nil {
+		t.Fatal(err)
+	}
 	oi.insert(context.Background(), doc)
 
 	if headNode.Docs[0].Deleted {
 		t.Errorf("Newly inserted documents should not be deleted")
 	}
 
-	oi.remove(context.Background(), doc.Key)
-
+	err = oi.tombstone(headNode.Identifier, doc.Key)
 	if err != nil {
 		t.Error(err)
 	}
@@ -114,35 +117,32 @@ func TestDeleteBlockList(t *testing.T) {
 	}
 }
 
-func TestUpdateBlockList(t *testing.T) {
+func newMockRepo(blockSize int) (repository.Repository, *repository.IOReporter) {
 	repo, reporter := repository.NewMockRepo()
-	oi := newBlocklist(2, repo)
-	oi.create()
-	doc := types.NewDoc("k")
 
-	headNode, err := oi.GetBlock(oi.Head)
-	if err != nil {
-		t.Error(err)
-	}
-	oi.insert(context.Background(), doc)
-
-	doc.Set(map[string]interface{}{
-		"LOL": 4,
-	})
+	return repository.WithFactory(repo, &BlockFactory{capacity: blockSize, repo: repo}), reporter
+}
 
-	oi.update(context.Background(), doc)
+// getN returns the first `n` documents yielded by a cursor
+// over `bl`
+func getN(t *testing.T, bl Blocklist, n int, asc bool) []types.Document {
+	t.Helper()
 
-	oi.repo.Flush()
-	if _, ok := reporter.Writes[headNode.ID()]; !ok {
-		t.Errorf("Node was not written")
+	cur, err := bl.Cursor(context.Background(), asc, "")
+	if err != nil {
+		t.Fatal(err)
 	}
 
-}
+	var out []types.Document
+	for len(out) < n && cur.Next() {
+		out = append(out, cur.Value())
+	}
 
-func newMockRepo(blockSize int) (repository.Repository, *repository.IOReporter) {
-	repo, reporter := repository.NewMockRepo()
+	if err := cur.Err(); err != nil {
+		t.Fatal(err)
+	}
 
-	return repository.WithFactory(repo, &BlockFactory{capacity: blockSize, repo: repo}), reporter
+	return out
 }
 
 func TestGetBlockList(t *testing.T) {
@@ -163,7 +163,7 @@ func TestGetBlockList(t *testing.T) {
 		bl.insert(ctx, d)
 		bl.insert(ctx, types.NewDoc("e"))
 
-		res := bl.GetN(4, false)
+		res := getN(t, bl, 4, false)
 
 		if len(res) != 4 {
 			t.Fatalf("Want %d Got %d", 4, len(res))
@@ -197,7 +197,7 @@ func TestGetBlockList(t *testing.T) {
 		oi.insert(ctx, d)
 		oi.insert(ctx, types.NewDoc("e"))
 
-		res := oi.GetN(100, false)
+		res := getN(t, oi, 100, false)
 
 		if len(res) != 4 {
 			t.Errorf("Want %d Got %d", 4, len(res))
@@ -231,7 +231,7 @@ func TestGetBlockList(t *testing.T) {
 		oi.insert(ctx, d)
 		oi.insert(ctx, types.NewDoc("e"))
 
-		res := oi.GetN(4, true)
+		res := getN(t, oi, 4, true)
 
 		if len(res) != 4 {
 			t.Errorf("Want %d Got %d", 4, len(res))
@@ -265,7 +265,7 @@ func TestGetBlockList(t *testing.T) {
 		oi.insert(ctx, d)
 		oi.insert(ctx, types.NewDoc("e"))
 
-		res := oi.GetN(100, true)
+		res := getN(t, oi, 100, true)
 
 		if len(res) != 4 {
 			t.Errorf("Want %d Got %d", 4, len(res))
@@ -307,3 +307,192 @@ func TestCreateBlocklist(t *testing.T) {
 		}
 	})
 }
+
+func TestInsertBatchBlockList(t *testing.T) {
+	ctx := context.Background()
+	repo, reporter := newMockRepo(2)
+	bl := newBlocklist(2, repo)
+	bl.create()
+	bl.insert(ctx, types.NewDoc("a"))
+
+	refs, err := bl.insertBatch(ctx, []types.Document{
+		types.NewDoc("b"),
+		types.NewDoc("c"),
+		types.NewDoc("d"),
+		types.NewDoc("e"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bl.repo.Flush()
+
+	if got := len(refs); got != 4 {
+		t.Fatalf("len(refs), Want=%d Got=%d", 4, got)
+	}
+
+	if bl.Docs != 5 {
+		t.Errorf("Docs, Want=%d Got=%d", 5, bl.Docs)
+	}
+
+	if bl.Blocks != 3 {
+		t.Errorf("Blocks, Want=%d Got=%d", 3, bl.Blocks)
+	}
+
+	if refs[1] != refs[2] || refs[0] == refs[1] || refs[2] == refs[3] {
+		t.Errorf("Expected each block to be filled before allocating a new one, got %v", refs)
+	}
+
+	for i, key := range []string{"b", "c", "d", "e"} {
+		b, err := bl.GetBlock(ID(refs[i]))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := b.Get(key); err != nil {
+			t.Errorf("%d: Expected key %s in block %s", i, key, refs[i])
+		}
+
+		if !reporter.Writes[refs[i]] {
+			t.Errorf("%d: Block %s was not written", i, refs[i])
+		}
+	}
+
+	res := getN(t, bl, 5, true)
+	for i, want := range []string{"a", "b", "c", "d", "e"} {
+		if got := res[i].Key; got != want {
+			t.Errorf("%d: Want key %s, got %s", i, want, got)
+		}
+	}
+}
+
+func TestBlocklistCursor(t *testing.T) {
+	ctx := context.Background()
+
+	newList := func() Blocklist {
+		repo, _ := newMockRepo(2)
+		bl := newBlocklist(2, repo)
+		bl.create()
+
+		d := types.NewDoc("d")
+		d.Deleted = true
+
+		for _, doc := range []types.Document{
+			types.NewDoc("a"),
+			types.NewDoc("b"),
+			types.NewDoc("c"),
+			d,
+			types.NewDoc("e"),
+		} {
+			bl.insert(ctx, doc)
+		}
+
+		return bl
+	}
+
+	page := func(t *testing.T, bl *Blocklist, n int, asc bool, after string) ([]string, string) {
+		t.Helper()
+
+		cur, err := bl.Cursor(ctx, asc, after)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var keys []string
+		for len(keys) < n && cur.Next() {
+			keys = append(keys, cur.Value().Key)
+		}
+
+		if err := cur.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		return keys, cur.Token()
+	}
+
+	for _, test := range []struct {
+		asc  bool
+		want [][]string
+	}{
+		{true, [][]string{{"a", "b"}, {"c", "e"}, nil}},
+		{false, [][]string{{"e", "c"}, {"b", "a"}, nil}},
+	} {
+		bl := newList()
+
+		after := ""
+		for i, want := range test.want {
+			var got []string
+			got, after = page(t, &bl, 2, test.asc, after)
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("asc=%v page %d: Want %v Got %v", test.asc, i, want, got)
+			}
+		}
+	}
+
+	t.Run("Resume after inserts", func(t *testing.T) {
+		bl := newList()
+
+		_, after := page(t, &bl, 4, true, "")
+		bl.insert(ctx, types.NewDoc("f"))
+		bl.insert(ctx, types.NewDoc("g"))
+
+		got, _ := page(t, &bl, 10, true, after)
+		if want := []string{"f", "g"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Want %v Got %v", want, got)
+		}
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		bl := newList()
+
+		_, after := page(t, &bl, 1, true, "")
+		if _, err := bl.Cursor(ctx, false, after); err == nil {
+			t.Errorf("Expected token from ascending cursor to be rejected")
+		}
+
+		if _, err := bl.Cursor(ctx, true, "garbage!"); err == nil {
+			t.Errorf("Expected malformed token to be rejected")
+		}
+	})
+
+	t.Run("Cancelled context", func(t *testing.T) {
+		bl := newList()
+		ctx, cancel := context.WithCancel(ctx)
+
+		cur, err := bl.Cursor(ctx, true, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cur.Next()
+		cancel()
+
+		if cur.Next() {
+			t.Errorf("Expected cursor to stop after cancellation")
+		}
+
+		if cur.Err() != context.Canceled {
+			t.Errorf("Want error %v, Got %v", context.Canceled, cur.Err())
+		}
+	})
+
+	t.Run("Storage error", func(t *testing.T) {
+		bl := newList()
+
+		tail, _ := bl.GetBlock(bl.Tail)
+		tail.Prev = "missing"
+		tail.save()
+
+		cur, err := bl.Cursor(ctx, true, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for cur.Next() {
+		}
+
+		if cur.Err() == nil {
+			t.Errorf("Expected error loading missing block")
+		}
+	})
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"strings"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/namvu9/keylime/src/errors"
 	"github.com/namvu9/keylime/src/index"
@@ -18,8 +20,33 @@ type Collection struct {
 	Name   string
 	Index  index.Index
 	Blocks Blocklist
+	Config types.CollectionConfig
 
-	repo repository.Repository
+	// Referrers are the fields of other collections that
+	// refer to documents in this collection
+	Referrers []Referrer
+
+	// Unique holds an index for each unique field of the
+	// schema, which maps values to document keys
+	Unique map[string]*index.Index
+
+	// Tombstones maps the keys of deleted documents to their
+	// last revision
+	Tombstones *index.Index
+
+	// Sequence is the last key generated by the SequenceKeys
+	// strategy
+	Sequence int64
+
+	CreatedAt time.Time
+
+	guard   guard
+	repo    repository.Repository
+	resolve func(name string) (*Collection, error)
+
+	// autoCreate makes the first write create the collection
+	// if it does not exist
+	autoCreate bool
 }
 
 func (c *Collection) ID() string {
@@ -27,8 +54,18 @@ func (c *Collection) ID() string {
 }
 
 // Get the value associated with the key `k`, if a record
-// with that key exists. Otherwise, nil is returned
+// with that key exists and has not expired. Otherwise, nil
+// is returned
 func (c *Collection) Get(ctx context.Context, k string) (*types.Document, error) {
+	if err := c.rlock(); err != nil {
+		return nil, err
+	}
+	defer c.runlock()
+
+	return c.get(ctx, k)
+}
+
+func (c *Collection) get(ctx context.Context, k string) (*types.Document, error) {
 	ref, err := c.Index.Get(ctx, k)
 	if err != nil {
 		return nil, err
@@ -44,6 +81,10 @@ func (c *Collection) Get(ctx context.Context, k string) (*types.Document, error)
 		return nil, err
 	}
 
+	if doc.Expired(time.Now()) {
+		return nil, errors.NewKeyNotFoundError("(*Collection).Get", k)
+	}
+
 	fullDoc := c.Schema.WithDefaults(*doc)
 	return &fullDoc, nil
 }
@@ -52,32 +93,419 @@ type Fields = map[string]interface{}
 
 // Set the value associated with key `k` in collection `c`.
 // If a record with that key already exists in the
-// collection, an error is returned.
-func (c *Collection) Set(ctx context.Context, k string, fields Fields) error {
+// collection, an error with code EConflict is returned.
+func (c *Collection) Set(ctx context.Context, k string, fields Fields, opts ...types.DocumentOption) error {
+	if err := c.ensure(ctx); err != nil {
+		return err
+	}
+
+	unlock, err := c.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return c.set(ctx, k, fields, opts...)
+}
+
+func (c *Collection) set(ctx context.Context, k string, fields Fields, opts ...types.DocumentOption) error {
 	log.Printf("Setting %s = %v in %s\n", k, fields, c.ID())
-	wrapError := errors.WrapWith("(*Collection).Set", errors.EInternal)
+	var op errors.Op = "(*Collection).Set"
+
+	doc, err := c.newDoc(ctx, k, fields, nil, opts...)
+	if err != nil {
+		return errors.Wrap(op, errors.EBadRequest, err)
+	}
+
+	ref, _, old, err := c.find(ctx, k)
+	switch {
+	case errors.GetKind(err) == errors.ENotFound:
+		err = c.insert(ctx, doc)
+	case err != nil:
+		return errors.Wrap(op, errors.EInternal, err)
+	case !old.Expired(time.Now()):
+		return errors.NewKeyExistsError(op, k)
+	default:
+		err = c.replace(ctx, *ref, *old, doc)
+	}
+
+	if err != nil {
+		return errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	log.Printf("Done setting %s in %s\n", k, c.ID())
+	return nil
+}
+
+// Upsert sets the value associated with key `k` in
+// collection `c`, replacing the existing document if a
+// record with that key already exists.
+func (c *Collection) Upsert(ctx context.Context, k string, fields Fields, opts ...types.DocumentOption) error {
+	if err := c.ensure(ctx); err != nil {
+		return err
+	}
+
+	unlock, err := c.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	log.Printf("Upserting %s = %v in %s\n", k, fields, c.ID())
+	var op errors.Op = "(*Collection).Upsert"
+
+	ref, _, old, err := c.find(ctx, k)
+	if err != nil && errors.GetKind(err) != errors.ENotFound {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	// The computed fields of a replaced document keep their
+	// values
+	doc, err := c.newDoc(ctx, k, fields, old, opts...)
+	if err != nil {
+		return errors.Wrap(op, errors.EBadRequest, err)
+	}
+
+	if old == nil {
+		err = c.insert(ctx, doc)
+	} else {
+		err = c.replace(ctx, *ref, *old, doc)
+	}
+
+	if err != nil {
+		return errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	log.Printf("Done upserting %s in %s\n", k, c.ID())
+	return nil
+}
+
+// newDoc creates a document with key `k` and the given
+// fields and options, along with the computed fields of the
+// schema. `stored` is the document that it replaces, if
+// any.
+func (c *Collection) newDoc(ctx context.Context, k string, fields Fields, stored *types.Document, opts ...types.DocumentOption) (types.Document, error) {
 	doc := types.NewDoc(k).Set(fields)
+	for _, opt := range opts {
+		opt(&doc)
+	}
+
+	c.applyDefaultTTL(&doc)
+	return c.Schema.Compute(ctx, doc, stored)
+}
+
+// applyDefaultTTL sets the expiry time of `doc` according
+// to the collection's default TTL, unless it already has
+// one
+func (c *Collection) applyDefaultTTL(doc *types.Document) {
+	if doc.ExpiresAt.IsZero() && c.Config.DefaultTTL > 0 {
+		doc.ExpiresAt = doc.LastModified.Add(c.Config.DefaultTTL)
+	}
+}
 
-	err := c.Schema.Validate(doc)
+// insert validates `doc` and writes it to the head of the
+// block list and the index
+func (c *Collection) insert(ctx context.Context, doc types.Document) error {
+	var op errors.Op = "(*Collection).insert"
+
+	err := c.validate(ctx, doc)
+	if err != nil {
+		return errors.Wrap(op, errors.EBadRequest, err)
+	}
+
+	err = c.checkUnique(ctx, doc, nil)
 	if err != nil {
-		return wrapError(err)
+		return errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	if doc.Revision == 0 {
+		doc.Revision, err = c.nextRevision(ctx, doc.Key)
+		if err != nil {
+			return errors.Wrap(op, errors.EInternal, err)
+		}
 	}
+	doc.Version = versionOf(doc.Revision)
 
 	blockID, err := c.Blocks.insert(ctx, doc)
+	if err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	if err := c.Index.Insert(ctx, doc.Key, blockID, doc.Version); err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	if err := c.indexUnique(ctx, nil, &doc); err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	err = c.commit()
+	if err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	return nil
+}
+
+// replace writes `doc` in place of `old`, the document
+// referenced by `ref`. The old document's block entry is
+// tombstoned so that it is no longer returned when listing
+// the collection.
+func (c *Collection) replace(ctx context.Context, ref index.Record, old, doc types.Document) error {
+	var op errors.Op = "(*Collection).replace"
+
+	doc.CreatedAt = old.CreatedAt
+	doc.Revision = old.Revision + 1
+
+	err := c.validate(ctx, doc)
+	if err != nil {
+		return errors.Wrap(op, errors.EBadRequest, err)
+	}
+
+	err = c.checkUnique(ctx, doc, nil)
+	if err != nil {
+		return errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	err = c.archive(old)
+	if err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	err = c.Blocks.tombstone(ID(ref.Value), ref.Key)
+	if err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	err = c.indexUnique(ctx, &old, nil)
+	if err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	return c.insert(ctx, doc)
+}
+
+// bulkLoadBatchSize is the number of documents buffered by
+// BulkLoad before they are written to the collection
+const bulkLoadBatchSize = 1000
+
+// BulkLoad inserts every document yielded by `it` into the
+// collection and returns the number of documents loaded.
+// Documents are validated and buffered in batches; each
+// batch fills blocks completely and is flushed once. If
+// keys arrive in ascending order, the index is built
+// bottom-up rather than through one insert per key. Keys
+// that already exist in the collection, or that appear
+// more than once, are rejected with code EConflict.
+func (c *Collection) BulkLoad(ctx context.Context, it types.DocumentIterator) (int, error) {
+	if err := c.ensure(ctx); err != nil {
+		return 0, err
+	}
+
+	unlock, err := c.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	log.Printf("Bulk loading documents into %s\n", c.ID())
+	var op errors.Op = "(*Collection).BulkLoad"
+
+	var (
+		n       = 0
+		batch   = make([]types.Document, 0, bulkLoadBatchSize)
+		pending = make(uniqueValues)
+		seen    = make(map[string]bool)
+	)
+
+	for it.Next() {
+		if err := ctx.Err(); err != nil {
+			return n, errors.Wrap(op, errors.EInternal, err)
+		}
+
+		doc := it.Value()
+		if _, err := c.Index.Get(ctx, doc.Key); err == nil || seen[doc.Key] {
+			return n, errors.Wrap(op, errors.EConflict, types.BatchError{doc.Key: errors.NewKeyExistsError(op, doc.Key)})
+		}
+		seen[doc.Key] = true
+
+		doc, err := c.Schema.Compute(ctx, doc, nil)
+		if err != nil {
+			return n, errors.Wrap(op, errors.EBadRequest, types.BatchError{doc.Key: err})
+		}
+
+		if err := c.validate(ctx, doc); err != nil {
+			return n, errors.Wrap(op, errors.EBadRequest, types.BatchError{doc.Key: err})
+		}
+
+		if err := c.checkUnique(ctx, doc, pending); err != nil {
+			return n, errors.Wrap(op, errors.GetKind(err), types.BatchError{doc.Key: err})
+		}
+
+		batch = append(batch, doc)
+
+		if len(batch) == bulkLoadBatchSize {
+			if err := c.loadBatch(ctx, batch); err != nil {
+				return n, errors.Wrap(op, errors.EInternal, err)
+			}
+
+			n += len(batch)
+			batch = batch[:0]
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		return n, errors.Wrap(op, errors.EBadRequest, err)
+	}
+
+	if err := c.loadBatch(ctx, batch); err != nil {
+		return n, errors.Wrap(op, errors.EInternal, err)
+	}
+	n += len(batch)
+
+	log.Printf("Done bulk loading %d documents into %s\n", n, c.ID())
+	return n, nil
+}
+
+func (c *Collection) loadBatch(ctx context.Context, docs []types.Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	for i := range docs {
+		rev, err := c.nextRevision(ctx, docs[i].Key)
+		if err != nil {
+			return err
+		}
+
+		c.applyDefaultTTL(&docs[i])
+		docs[i].Revision = rev
+		docs[i].Version = versionOf(rev)
+	}
+
+	refs, err := c.Blocks.insertBatch(ctx, docs)
 	if err != nil {
 		return err
 	}
 
-	if err := c.Index.Insert(ctx, k, blockID, doc.Hash()); err != nil {
+	records := make([]index.Record, len(docs))
+	for i, doc := range docs {
+		records[i] = index.Record{Key: doc.Key, Value: refs[i], Hash: doc.Version}
+	}
+
+	err = c.Index.BulkInsert(ctx, records)
+	if err != nil {
 		return err
 	}
 
-	err = c.repo.Flush()
+	for i := range docs {
+		if err := c.indexUnique(ctx, nil, &docs[i]); err != nil {
+			return err
+		}
+	}
+
+	return c.commit()
+}
+
+// GetMany returns the documents with the given keys in the
+// order they were requested. The entry for a key that does
+// not exist in the collection is nil.
+func (c *Collection) GetMany(ctx context.Context, keys []string) ([]*types.Document, error) {
+	if err := c.rlock(); err != nil {
+		return nil, err
+	}
+	defer c.runlock()
+
+	var op errors.Op = "(*Collection).GetMany"
+
+	out := make([]*types.Document, len(keys))
+	for i, k := range keys {
+		doc, err := c.get(ctx, k)
+		if errors.GetKind(err) == errors.ENotFound {
+			continue
+		} else if err != nil {
+			return nil, errors.Wrap(op, errors.EInternal, err)
+		}
+
+		out[i] = doc
+	}
+
+	return out, nil
+}
+
+// SetMany writes a batch of documents, keyed by their
+// document keys, to the collection. Every document is
+// validated before anything is written, and all blocks and
+// index changes are flushed at once. Documents whose keys
+// already exist in the collection are rejected. In AllOrNothing mode,
+// no documents are written if any of them is invalid. In
+// Partial mode, the valid documents are written. Either
+// way, rejected documents are reported in a
+// types.BatchError.
+func (c *Collection) SetMany(ctx context.Context, docs map[string]Fields, mode types.BatchMode) error {
+	if err := c.ensure(ctx); err != nil {
+		return err
+	}
+
+	unlock, err := c.lock()
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
-	log.Printf("Done setting %s in %s\n", k, c.ID())
+	log.Printf("Setting %d documents in %s\n", len(docs), c.ID())
+	var op errors.Op = "(*Collection).SetMany"
+
+	var keys []string
+	for k := range docs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var (
+		batch    []types.Document
+		rejected = make(types.BatchError)
+		pending  = make(uniqueValues)
+	)
+
+	for _, k := range keys {
+		if _, err := c.Index.Get(ctx, k); err == nil {
+			rejected[k] = errors.NewKeyExistsError(op, k)
+			continue
+		}
+
+		doc, err := c.newDoc(ctx, k, docs[k], nil)
+		if err != nil {
+			rejected[k] = err
+			continue
+		}
+
+		if err := c.validate(ctx, doc); err != nil {
+			rejected[k] = err
+			continue
+		}
+
+		if err := c.checkUnique(ctx, doc, pending); err != nil {
+			rejected[k] = err
+			continue
+		}
+
+		batch = append(batch, doc)
+	}
+
+	if len(rejected) > 0 && mode == types.AllOrNothing {
+		return errors.Wrap(op, errors.EBadRequest, rejected)
+	}
+
+	if err := c.loadBatch(ctx, batch); err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	if len(rejected) > 0 {
+		return errors.Wrap(op, errors.EBadRequest, rejected)
+	}
+
+	log.Printf("Done setting %d documents in %s\n", len(batch), c.ID())
 	return nil
 }
 
@@ -90,52 +518,306 @@ func (c *Collection) commit() error {
 	return c.repo.Flush()
 }
 
-func (c *Collection) GetLast(ctx context.Context, n int) ([]types.Document, error) {
-	return c.Blocks.GetN(n, false), nil
+// GetLast returns the `n` most recently inserted documents,
+// newest first, or the `n` documents with the greatest keys
+// if opts.ByKey is set
+func (c *Collection) GetLast(ctx context.Context, n int, opts types.ListOptions) (*types.Page, error) {
+	if err := c.rlock(); err != nil {
+		return nil, err
+	}
+	defer c.runlock()
+
+	var op errors.Op = "(*Collection).GetLast"
+
+	page, err := c.page(ctx, n, false, opts)
+	if err != nil {
+		return nil, errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	return page, nil
 }
 
-func (c *Collection) GetFirst(ctx context.Context, n int) ([]types.Document, error) {
-	// TODO: return error from oi
-	return c.Blocks.GetN(n, true), nil
+// GetFirst returns the `n` least recently inserted
+// documents, oldest first, or the `n` documents with the
+// smallest keys if opts.ByKey is set
+func (c *Collection) GetFirst(ctx context.Context, n int, opts types.ListOptions) (*types.Page, error) {
+	if err := c.rlock(); err != nil {
+		return nil, err
+	}
+	defer c.runlock()
+
+	var op errors.Op = "(*Collection).GetFirst"
+
+	page, err := c.page(ctx, n, true, opts)
+	if err != nil {
+		return nil, errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	return page, nil
+}
+
+// Scan returns a cursor over the documents in the
+// collection. Documents are visited in insertion order, or
+// in key order if opts.ByKey is set, and in reverse if
+// `asc` is false. The cursor holds the collection's guard
+// shared while it advances, but not in between.
+func (c *Collection) Scan(ctx context.Context, asc bool, opts types.ListOptions) (types.Cursor, error) {
+	if err := c.rlock(); err != nil {
+		return nil, err
+	}
+	defer c.runlock()
+
+	cur, err := c.scan(ctx, asc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &guardedCursor{Cursor: cur, c: c}, nil
 }
 
+func (c *Collection) scan(ctx context.Context, asc bool, opts types.ListOptions) (types.Cursor, error) {
+	var op errors.Op = "(*Collection).Scan"
+
+	cur, err := c.cursor(ctx, asc, opts)
+	if err != nil {
+		return nil, errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	for i := 0; i < opts.Skip && cur.Next(); i++ {
+	}
+
+	return cur, nil
+}
+
+func (c *Collection) cursor(ctx context.Context, asc bool, opts types.ListOptions) (types.Cursor, error) {
+	var op errors.Op = "(*Collection).cursor"
+
+	if !opts.ByKey {
+		cur, err := c.Blocks.Cursor(ctx, asc, opts.After)
+		if err != nil {
+			return nil, errors.Wrap(op, errors.EBadRequest, err)
+		}
+
+		return cur, nil
+	}
+
+	var after string
+	if opts.After != "" {
+		k, err := decodeKeyToken(opts.After, asc)
+		if err != nil {
+			return nil, errors.Wrap(op, errors.EBadRequest, err)
+		}
+		after = k
+	}
+
+	it, err := c.Index.Cursor(ctx, asc, after)
+	if err != nil {
+		return nil, errors.Wrap(op, errors.EInternal, err)
+	}
+
+	return &keyCursor{c: c, it: it, asc: asc, now: time.Now(), key: after}, nil
+}
+
+// page reads up to `n` documents from a cursor, and one
+// more to determine whether the listing continues
+func (c *Collection) page(ctx context.Context, n int, asc bool, opts types.ListOptions) (*types.Page, error) {
+	if len(opts.OrderBy) > 0 {
+		return c.sortedPage(ctx, n, asc, opts)
+	}
+
+	cur, err := c.scan(ctx, asc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &types.Page{Documents: []types.Document{}}
+	for len(page.Documents) < n && cur.Next() {
+		page.Documents = append(page.Documents, c.Schema.WithDefaults(cur.Value()))
+	}
+
+	page.Cursor = cur.Token()
+	if len(page.Documents) == n {
+		page.HasMore = cur.Next()
+	}
+
+	if err := cur.Err(); err != nil {
+		return nil, errors.Wrap("(*Collection).page", errors.EInternal, err)
+	}
+
+	return page, nil
+}
+
+// Update the fields of the document with key `k`. An error
+// with code ENotFound is returned if no such document
+// exists.
 func (c *Collection) Update(ctx context.Context, k string, fields map[string]interface{}) error {
-	// Retrieve record
-	wrapError := errors.WrapWith("(*Collection).Update", errors.EInternal)
-	ref, err := c.Index.Get(ctx, k)
+	unlock, err := c.lock()
 	if err != nil {
-		return wrapError(err)
+		return err
 	}
+	defer unlock()
 
-	block, err := c.Blocks.GetBlock(ID(ref.Value))
+	return c.update(ctx, k, "", func(doc types.Document) (types.Document, error) {
+		return doc.Update(fields), nil
+	})
+}
+
+// UpdateIf updates the fields of the document with key `k`
+// if its current version is `version`. Otherwise, an error
+// with code EVersionMismatch is returned.
+func (c *Collection) UpdateIf(ctx context.Context, k string, version string, fields map[string]interface{}) error {
+	unlock, err := c.lock()
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
-	doc, err := block.Get(k)
+	return c.update(ctx, k, version, func(doc types.Document) (types.Document, error) {
+		return doc.Update(fields), nil
+	})
+}
+
+// Apply the update operators to the document with key `k`.
+// Either every operator is applied or, if one of them fails
+// or the result does not conform to the schema, none are.
+func (c *Collection) Apply(ctx context.Context, k string, ops []types.UpdateOp) error {
+	return c.ApplyIf(ctx, k, "", ops)
+}
+
+// ApplyIf applies the update operators to the document with
+// key `k` if its current version is `version`
+func (c *Collection) ApplyIf(ctx context.Context, k string, version string, ops []types.UpdateOp) error {
+	unlock, err := c.lock()
 	if err != nil {
 		return err
 	}
+	defer unlock()
+
+	return c.update(ctx, k, version, func(doc types.Document) (types.Document, error) {
+		newDoc, err := doc.Apply(ops...)
+		if err != nil {
+			return newDoc, errors.Wrap("(*Collection).Apply", errors.EBadRequest, err)
+		}
+
+		return newDoc, nil
+	})
+}
+
+// update the document with key `k` to the result of
+// `modify`. If `version` is not empty, the update is
+// rejected unless it matches the stored document's version.
+func (c *Collection) update(ctx context.Context, k string, version string, modify func(types.Document) (types.Document, error)) error {
+	var op errors.Op = "(*Collection).Update"
+
+	ref, block, doc, err := c.find(ctx, k)
+	if err != nil {
+		return errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	if doc.Expired(time.Now()) {
+		return errors.NewKeyNotFoundError(op, k)
+	}
 
-	if ref.Hash != doc.Hash() {
-		return fmt.Errorf("Hashes did not match: Want=%s Got=%s", ref.Hash, doc.Hash())
+	if version != "" && version != doc.Version {
+		return errors.NewVersionMismatchError(op, k, version, doc.Version)
 	}
 
-	err = block.Update(doc.Update(fields))
+	newDoc, err := modify(*doc)
 	if err != nil {
-		return wrapError(err)
+		return errors.Wrap(op, errors.GetKind(err), err)
 	}
 
-	err = c.repo.Save(block)
+	newDoc, err = c.Schema.Compute(ctx, newDoc, doc)
 	if err != nil {
-		wrapError(err)
+		return errors.Wrap(op, errors.EBadRequest, err)
+	}
+
+	if err := c.validate(ctx, newDoc); err != nil {
+		return errors.Wrap(op, errors.EBadRequest, err)
+	}
+
+	if err := c.checkUnique(ctx, newDoc, nil); err != nil {
+		return errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	newDoc.Revision = doc.Revision + 1
+	newDoc.Version = versionOf(newDoc.Revision)
+
+	err = c.archive(*doc)
+	if err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	err = block.Update(newDoc)
+	if err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	err = c.Index.Insert(ctx, k, ref.Value, newDoc.Version)
+	if err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	err = c.indexUnique(ctx, doc, &newDoc)
+	if err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
 	}
 
 	return c.commit()
 }
 
+// versionOf returns the version of revision `rev` of a
+// document. Unlike a hash of the fields, it changes on every
+// write, such that a version is never valid again once the
+// document has been written.
+func versionOf(rev int) string {
+	return strconv.Itoa(rev)
+}
+
+// find returns a copy of the index record, the block and
+// the document for key `k`. An error with code ENotFound is
+// returned if no such document exists.
+func (c *Collection) find(ctx context.Context, k string) (*index.Record, *Block, *types.Document, error) {
+	var op errors.Op = "(*Collection).find"
+
+	ref, err := c.Index.Get(ctx, k)
+	if errors.GetKind(err) == errors.ENotFound {
+		return nil, nil, nil, err
+	} else if err != nil {
+		return nil, nil, nil, errors.Wrap(op, errors.EInternal, err)
+	}
+
+	block, err := c.Blocks.GetBlock(ID(ref.Value))
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(op, errors.EInternal, err)
+	}
+
+	doc, err := block.Get(k)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(op, errors.EInternal, err)
+	}
+
+	if ref.Hash != doc.Version {
+		return nil, nil, nil, errors.Wrap(op, errors.EInternal, fmt.Errorf("Versions did not match: Want=%s Got=%s", ref.Hash, doc.Version))
+	}
+
+	// The index returns a pointer into its node, which is
+	// overwritten when the index is modified
+	rec := *ref
+
+	return &rec, block, doc, nil
+}
+
 // TODO: If this fails, clean up
-func (c *Collection) Create(ctx context.Context, s *types.Schema) error {
+func (c *Collection) Create(ctx context.Context, s *types.Schema, opts ...types.CollectionOption) error {
+	// Creations of the same collection are serialized, such
+	// that only the first succeeds
+	unlock, err := c.lockCreate(s)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	log.Printf("Creating collection %s\n", c.ID())
 	var op errors.Op = "(*Collection).Create"
 
@@ -143,8 +825,18 @@ func (c *Collection) Create(ctx context.Context, s *types.Schema) error {
 		c.Schema = *s
 	}
 
+	for _, opt := range opts {
+		opt(&c.Config)
+	}
+
+	if c.Config.Keys != "" && !types.KeyStrategies[c.Config.Keys] {
+		return errors.Wrap(op, errors.EBadRequest, fmt.Errorf("Unknown key strategy: %s", c.Config.Keys))
+	}
+
+	c.CreatedAt = time.Now().UTC()
+
 	c.Blocks = newBlocklist(200, c.repo)
-	err := c.Blocks.create()
+	err = c.Blocks.create()
 	if err != nil {
 		return errors.Wrap(op, errors.EInternal, err)
 	}
@@ -155,6 +847,23 @@ func (c *Collection) Create(ctx context.Context, s *types.Schema) error {
 		return errors.Wrap(op, errors.EInternal, err)
 	}
 
+	err = c.createUniqueIndexes()
+	if err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	tombstones := index.New(50, c.repo)
+	err = tombstones.Create()
+	if err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+	c.Tombstones = &tombstones
+
+	err = c.addReferrers()
+	if err != nil {
+		return errors.Wrap(op, errors.GetKind(err), err)
+	}
+
 	err = c.repo.Save(c)
 	if err != nil {
 		return errors.Wrap(op, errors.EInternal, err)
@@ -165,55 +874,250 @@ func (c *Collection) Create(ctx context.Context, s *types.Schema) error {
 		return errors.Wrap(op, errors.EInternal, err)
 	}
 
+	c.guard.created = true
+
 	log.Printf("Done creating collection %s\n", c.ID())
 	return nil
 }
 
 // Delete record with key `k`. An error is returned of no
-// such record exists
+// such record exists. The on-delete policies of the fields
+// that refer to the collection are applied: the delete is
+// rejected with code EConflict if a Restrict field refers to
+// the record, and the records that refer to it through
+// Cascade or SetNull fields are deleted or have the field
+// removed.
 func (c *Collection) Delete(ctx context.Context, k string) error {
+	return c.DeleteIf(ctx, k, "")
+}
+
+// DeleteIf deletes the record with key `k` if its current
+// version is `version`. Otherwise, an error with code
+// EVersionMismatch is returned.
+func (c *Collection) DeleteIf(ctx context.Context, k string, version string) error {
+	unlock, err := c.lockDelete()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return c.delete(ctx, k, version)
+}
+
+func (c *Collection) delete(ctx context.Context, k string, version string) error {
 	var op errors.Op = "(*Collection).Delete"
 
-	err := c.Index.Delete(ctx, k)
+	ref, _, doc, err := c.find(ctx, k)
+	if err != nil {
+		return errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	if version != "" && version != doc.Version {
+		return errors.NewVersionMismatchError(op, k, version, doc.Version)
+	}
+
+	if err := c.checkRestrict(ctx, k); err != nil {
+		return errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	deleted := *doc
+	deleted.Deleted = true
+	err = c.archive(deleted)
 	if err != nil {
 		return errors.Wrap(op, errors.EInternal, err)
 	}
 
-	err = c.Blocks.remove(ctx, k)
+	err = c.Index.Delete(ctx, k)
 	if err != nil {
 		return errors.Wrap(op, errors.EInternal, err)
 	}
 
-	return c.commit()
+	err = c.addTombstone(ctx, *doc)
+	if err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	err = c.Blocks.tombstone(ID(ref.Value), k)
+	if err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	err = c.indexUnique(ctx, doc, nil)
+	if err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	err = c.commit()
+	if err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	return c.applyReferrers(ctx, k)
 }
 
-func (c *Collection) Info(ctx context.Context) string {
-	if ok, err := c.repo.Exists(c.ID()); !ok && err == nil {
-		return fmt.Sprintf("Collection %s does not exist", c.ID())
-	} else if err != nil {
-		return fmt.Sprintf("Error: %s", err)
+// Reap deletes every document in the collection that has
+// expired and returns the number of deleted documents
+func (c *Collection) Reap(ctx context.Context) (int, error) {
+	var op errors.Op = "(*Collection).Reap"
+
+	unlock, err := c.lockDelete()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	deleted, err := c.reap(ctx)
+	if err != nil {
+		return len(deleted), errors.Wrap(op, errors.GetKind(err), err)
 	}
 
-	var sb strings.Builder
-	sb.WriteString("\n---------------\n")
-	sb.WriteString(fmt.Sprintf("Collection: %s\n", c.ID()))
-	sb.WriteString("---------------\n")
+	if len(deleted) > 0 {
+		log.Printf("Reaped %d expired documents in %s\n", len(deleted), c.ID())
+	}
+
+	return len(deleted), nil
+}
+
+// reap deletes the expired documents and returns their keys
+func (c *Collection) reap(ctx context.Context) ([]string, error) {
+	keys, err := c.Blocks.expired(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	for _, k := range keys {
+		err := c.delete(ctx, k, "")
+		if errors.GetKind(err) == errors.ENotFound {
+			continue
+		} else if err != nil {
+			return deleted, err
+		}
+
+		deleted = append(deleted, k)
+	}
+
+	return deleted, nil
+}
+
+// GetSchema returns the schema of the collection, which is
+// empty if the collection is schemaless
+func (c *Collection) GetSchema(ctx context.Context) (types.Schema, error) {
+	if err := c.rlock(); err != nil {
+		return types.Schema{}, err
+	}
+	defer c.runlock()
+
+	var op errors.Op = "(*Collection).GetSchema"
+
+	if ok, err := c.repo.Exists(c.ID()); err != nil {
+		return types.Schema{}, errors.Wrap(op, errors.EInternal, err)
+	} else if !ok {
+		return types.Schema{}, errors.NewCollectionNotFoundError(op, c.ID())
+	}
+
+	return c.Schema, nil
+}
+
+// Info returns the stats of the collection. An error with
+// code ENotFound is returned if the collection does not
+// exist.
+func (c *Collection) Info(ctx context.Context) (*types.CollectionStats, error) {
+	if err := c.rlock(); err != nil {
+		return nil, err
+	}
+	defer c.runlock()
+
+	var op errors.Op = "(*Collection).Info"
+
+	if ok, err := c.repo.Exists(c.ID()); err != nil {
+		return nil, errors.Wrap(op, errors.EInternal, err)
+	} else if !ok {
+		return nil, errors.NewCollectionNotFoundError(op, c.ID())
+	}
 
-	schema := c.Schema.String()
-	if schema != "" {
-		sb.WriteString(schema)
+	stats := &types.CollectionStats{
+		Name:      c.ID(),
+		CreatedAt: c.CreatedAt,
+		Config:    c.Config,
+		Schema:    c.Schema.Describe(),
+		Docs:      c.Index.Records,
+		Bytes:     make(map[string]int64),
 	}
-	sb.WriteString("\n")
-	sb.WriteString(c.Index.Info())
-	sb.WriteString("\n")
-	sb.WriteString(c.Blocks.Info())
-	sb.WriteString("\n")
 
-	return sb.String()
+	var err error
+	stats.Index, err = c.Index.Info()
+	if err != nil {
+		return nil, errors.Wrap(op, errors.EInternal, err)
+	}
+
+	stats.Blocks, err = c.Blocks.Info()
+	if err != nil {
+		return nil, errors.Wrap(op, errors.EInternal, err)
+	}
+	stats.Deleted = stats.Blocks.Deleted
+
+	var unique int64
+	for field, idx := range c.Unique {
+		if stats.Unique == nil {
+			stats.Unique = make(map[string]types.IndexStats)
+		}
+
+		s, err := idx.Info()
+		if err != nil {
+			return nil, errors.Wrap(op, errors.EInternal, err)
+		}
+
+		stats.Unique[field] = s
+		unique += s.Bytes
+	}
+
+	var tombstones int64
+	if c.Tombstones != nil {
+		s, err := c.Tombstones.Info()
+		if err != nil {
+			return nil, errors.Wrap(op, errors.EInternal, err)
+		}
+
+		tombstones = s.Bytes
+	}
+
+	header, err := c.repo.Size(c.ID())
+	if err != nil {
+		return nil, errors.Wrap(op, errors.EInternal, err)
+	}
+
+	history, err := c.historySize()
+	if err != nil {
+		return nil, errors.Wrap(op, errors.EInternal, err)
+	}
+
+	total, err := c.repo.DiskUsage()
+	if err != nil {
+		return nil, errors.Wrap(op, errors.EInternal, err)
+	}
+
+	stats.Bytes["total"] = total
+	stats.Bytes["header"] = header
+	stats.Bytes["index"] = stats.Index.Bytes
+	stats.Bytes["unique"] = unique
+	stats.Bytes["blocks"] = stats.Blocks.Bytes
+	stats.Bytes["tombstones"] = tombstones
+	stats.Bytes["history"] = history
+
+	return stats, nil
 }
 
 func (c *Collection) load() error {
+	c.initGuard()
+	c.guard.created = true
 	c.Index.SetRepo(c.repo)
+	for _, idx := range c.Unique {
+		idx.SetRepo(c.repo)
+	}
+	if c.Tombstones != nil {
+		c.Tombstones.SetRepo(c.repo)
+	}
 	c.Blocks.repo = repository.WithFactory(c.repo, &BlockFactory{200, c.repo})
 	return nil
 }
@@ -223,6 +1127,7 @@ func newCollection(name string, r repository.Repository) *Collection {
 		Name: name,
 		repo: repository.WithScope(r, name),
 	}
+	c.initGuard()
 
 	return c
 }
@@ -0,0 +1,1566 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/namvu9/keylime/src/errors"
+	"github.com/namvu9/keylime/src/repository"
+	"github.com/namvu9/keylime/src/types"
+)
+
+func newTestCollection(t *testing.T, s *types.Schema) *Collection {
+	repo, _ := repository.NewMockRepo()
+	c := newCollection("test", repo)
+
+	if err := c.Create(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestSetMany(t *testing.T) {
+	ctx := context.Background()
+	schema, _ := types.NewSchemaBuilder().AddField("age", types.Number).Build()
+
+	docs := map[string]Fields{
+		"a": {"age": 1.0},
+		"b": {"age": "not a number"},
+		"c": {"age": 3.0},
+	}
+
+	t.Run("All or nothing", func(t *testing.T) {
+		c := newTestCollection(t, &schema)
+
+		err := c.SetMany(ctx, docs, types.AllOrNothing)
+		if errors.GetKind(err) != errors.EBadRequest {
+			t.Fatalf("Want error with code %s, Got %v", errors.EBadRequest, err)
+		}
+
+		if c.Blocks.Docs != 0 || c.Index.Records != 0 {
+			t.Errorf("Expected no documents to be written, Got %d docs and %d records", c.Blocks.Docs, c.Index.Records)
+		}
+	})
+
+	t.Run("Partial", func(t *testing.T) {
+		c := newTestCollection(t, &schema)
+
+		err := c.SetMany(ctx, docs, types.Partial)
+		werr, ok := err.(*errors.Error)
+		if !ok {
+			t.Fatalf("Expected *errors.Error, Got %v", err)
+		}
+
+		rejected, ok := werr.Err.(types.BatchError)
+		if !ok || len(rejected) != 1 || rejected["b"] == nil {
+			t.Errorf("Expected document b to be rejected, Got %v", werr.Err)
+		}
+
+		res, err := c.GetMany(ctx, []string{"c", "b", "a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, want := range []string{"c", "", "a"} {
+			if want == "" && res[i] != nil {
+				t.Errorf("%d: Want nil, Got %v", i, res[i])
+			} else if want != "" && (res[i] == nil || res[i].Key != want) {
+				t.Errorf("%d: Want key %s, Got %v", i, want, res[i])
+			}
+		}
+	})
+}
+
+func TestBulkLoad(t *testing.T) {
+	ctx := context.Background()
+
+	load := func(c *Collection, keys ...string) (int, error) {
+		var docs []types.Document
+		for i, k := range keys {
+			docs = append(docs, types.NewDoc(k).Set(Fields{"n": float64(i)}))
+		}
+
+		return c.BulkLoad(ctx, &sliceIterator{docs: docs, i: -1})
+	}
+
+	t.Run("Existing key", func(t *testing.T) {
+		c := newTestCollection(t, nil)
+		c.Set(ctx, "b", Fields{"n": 10.0})
+
+		_, err := load(c, "a", "b", "c")
+		if errors.GetKind(err) != errors.EConflict {
+			t.Fatalf("Want error with code %s, Got %v", errors.EConflict, err)
+		}
+
+		if c.Index.Records != 1 || c.Blocks.Docs != 1 {
+			t.Errorf("Want 1 record and 1 doc, Got %d and %d", c.Index.Records, c.Blocks.Docs)
+		}
+
+		doc, err := c.Get(ctx, "b")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := doc.Fields["n"].Value; got != 10.0 {
+			t.Errorf("Want n=10, Got %v", got)
+		}
+
+		if err := c.Upsert(ctx, "b", Fields{"n": 11.0}); err != nil {
+			t.Errorf("Upsert after rejected load: %s", err)
+		}
+	})
+
+	t.Run("Repeated key", func(t *testing.T) {
+		c := newTestCollection(t, nil)
+
+		_, err := load(c, "a", "b", "a")
+		if errors.GetKind(err) != errors.EConflict {
+			t.Fatalf("Want error with code %s, Got %v", errors.EConflict, err)
+		}
+
+		if c.Index.Records != 0 || c.Blocks.Docs != 0 {
+			t.Errorf("Expected no documents to be loaded, Got %d records and %d docs", c.Index.Records, c.Blocks.Docs)
+		}
+	})
+}
+
+func TestOpenImport(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "import")
+	os.Mkdir(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "users.jsonl"), nil, 0644)
+	os.WriteFile(filepath.Join(base, "secret"), nil, 0644)
+	os.Symlink(filepath.Join(base, "secret"), filepath.Join(dir, "link"))
+
+	s := New(&Config{BaseDir: t.TempDir(), ImportDir: dir})
+
+	for _, path := range []string{"users.jsonl", "./users.jsonl", filepath.Join(dir, "users.jsonl")} {
+		f, err := s.OpenImport(path)
+		if err != nil {
+			t.Errorf("%s: %s", path, err)
+			continue
+		}
+		f.Close()
+	}
+
+	for _, path := range []string{"../secret", filepath.Join(base, "secret"), "link", "missing"} {
+		if _, err := s.OpenImport(path); errors.GetKind(err) != errors.EBadRequest {
+			t.Errorf("%s: Want error with code %s, Got %v", path, errors.EBadRequest, err)
+		}
+	}
+
+	s = New(&Config{BaseDir: t.TempDir()})
+	if _, err := s.OpenImport(filepath.Join(dir, "users.jsonl")); errors.GetKind(err) != errors.EBadRequest {
+		t.Errorf("No import directory: Want error with code %s, Got %v", errors.EBadRequest, err)
+	}
+}
+
+func TestSetModes(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Set existing key", func(t *testing.T) {
+		c := newTestCollection(t, nil)
+		c.Set(ctx, "a", Fields{"n": 1.0})
+
+		err := c.Set(ctx, "a", Fields{"n": 2.0})
+		if errors.GetKind(err) != errors.EConflict {
+			t.Errorf("Want error with code %s, Got %v", errors.EConflict, err)
+		}
+
+		if got := len(getN(t, c.Blocks, 10, true)); got != 1 {
+			t.Errorf("Want 1 document, Got %d", got)
+		}
+	})
+
+	t.Run("Upsert", func(t *testing.T) {
+		c := newTestCollection(t, nil)
+
+		if err := c.Upsert(ctx, "a", Fields{"n": 1.0}); err != nil {
+			t.Fatal(err)
+		}
+		c.Set(ctx, "b", Fields{"n": 1.0})
+
+		old, _ := c.Get(ctx, "a")
+		if err := c.Upsert(ctx, "a", Fields{"n": 2.0}); err != nil {
+			t.Fatal(err)
+		}
+
+		doc, err := c.Get(ctx, "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := doc.Fields["n"].Value; got != 2.0 {
+			t.Errorf("Want n=2, Got %v", got)
+		}
+
+		if !doc.CreatedAt.Equal(old.CreatedAt) {
+			t.Errorf("Expected CreatedAt to be preserved")
+		}
+
+		res := getN(t, c.Blocks, 10, false)
+		if len(res) != 2 || res[0].Key != "a" || res[1].Key != "b" {
+			t.Errorf("Expected stale entry of a to be tombstoned, Got %v", res)
+		}
+
+		if c.Blocks.Docs != 2 || c.Index.Records != 2 {
+			t.Errorf("Want 2 docs and 2 records, Got %d and %d", c.Blocks.Docs, c.Index.Records)
+		}
+	})
+
+	t.Run("Update missing key", func(t *testing.T) {
+		c := newTestCollection(t, nil)
+
+		err := c.Update(ctx, "a", Fields{"n": 2.0})
+		if errors.GetKind(err) != errors.ENotFound {
+			t.Errorf("Want error with code %s, Got %v", errors.ENotFound, err)
+		}
+	})
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCollection(t, nil)
+
+	for i := 0; i < 300; i++ {
+		if err := c.Set(ctx, fmt.Sprintf("k%d", i), Fields{"i": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Deleting in key order merges index nodes
+	for i := 0; i < 300; i++ {
+		k := fmt.Sprintf("k%d", i)
+		if err := c.Delete(ctx, k); err != nil {
+			t.Fatalf("Delete %s: %s", k, err)
+		}
+	}
+
+	if n, err := c.Count(ctx, nil); err != nil || n != 0 {
+		t.Errorf("Want 0 documents, Got %d (%v)", n, err)
+	}
+
+	if c.Blocks.Docs != 0 || c.Index.Records != 0 {
+		t.Errorf("Want 0 docs and 0 records, Got %d and %d", c.Blocks.Docs, c.Index.Records)
+	}
+}
+
+func TestConditionalWrites(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCollection(t, nil)
+	c.Set(ctx, "a", Fields{"n": 1.0, "s": "x", "o": map[string]interface{}{"a": 1.0, "b": 2.0}})
+
+	doc, _ := c.Get(ctx, "a")
+	if doc.Version == "" {
+		t.Fatalf("Expected document to have a version")
+	}
+
+	if err := c.UpdateIf(ctx, "a", doc.Version, Fields{"n": 2.0}); err != nil {
+		t.Fatalf("Update with current version: %s", err)
+	}
+
+	err := c.UpdateIf(ctx, "a", doc.Version, Fields{"n": 3.0})
+	if errors.GetKind(err) != errors.EVersionMismatch {
+		t.Errorf("Update with stale version: Want error with code %s, Got %v", errors.EVersionMismatch, err)
+	}
+
+	err = c.DeleteIf(ctx, "a", doc.Version)
+	if errors.GetKind(err) != errors.EVersionMismatch {
+		t.Errorf("Delete with stale version: Want error with code %s, Got %v", errors.EVersionMismatch, err)
+	}
+
+	doc, _ = c.Get(ctx, "a")
+	if got := doc.Fields["n"].Value; got != 2.0 {
+		t.Errorf("Want n=2, Got %v", got)
+	}
+
+	if err := c.DeleteIf(ctx, "a", doc.Version); err != nil {
+		t.Fatalf("Delete with current version: %s", err)
+	}
+
+	if _, err := c.Get(ctx, "a"); errors.GetKind(err) != errors.ENotFound {
+		t.Errorf("Expected document to be deleted, Got %v", err)
+	}
+
+	// Restoring the fields of a stale read does not make its
+	// version current again
+	c.Set(ctx, "b", Fields{"n": 1.0})
+	stale, _ := c.Get(ctx, "b")
+	c.Update(ctx, "b", Fields{"n": 2.0})
+	c.Update(ctx, "b", Fields{"n": 1.0})
+
+	err = c.UpdateIf(ctx, "b", stale.Version, Fields{"n": 3.0})
+	if errors.GetKind(err) != errors.EVersionMismatch {
+		t.Errorf("Update after A-B-A: Want error with code %s, Got %v", errors.EVersionMismatch, err)
+	}
+
+	// Nor does a write that only changes the expiry time
+	c.Set(ctx, "c", Fields{"n": 1.0})
+	stale, _ = c.Get(ctx, "c")
+	c.Upsert(ctx, "c", Fields{"n": 1.0}, types.WithTTL(time.Hour))
+
+	err = c.DeleteIf(ctx, "c", stale.Version)
+	if errors.GetKind(err) != errors.EVersionMismatch {
+		t.Errorf("Delete after TTL change: Want error with code %s, Got %v", errors.EVersionMismatch, err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := repository.NewMockRepo()
+	c := newCollection("test", repo)
+	c.Create(ctx, nil, types.WithHistory(2))
+
+	c.Set(ctx, "a", Fields{"n": 1.0})
+	c.Update(ctx, "a", Fields{"n": 2.0})
+	c.Upsert(ctx, "a", Fields{"n": 3.0})
+	c.Update(ctx, "a", Fields{"n": 4.0})
+
+	doc, err := c.Get(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Revision != 4 {
+		t.Errorf("Revision, Want=4 Got=%d", doc.Revision)
+	}
+
+	revisions, err := c.History(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []float64{2, 3, 4} {
+		if i >= len(revisions) {
+			t.Fatalf("Want 3 revisions, Got %d", len(revisions))
+		}
+
+		if got := revisions[i].Fields["n"].Value; got != want || revisions[i].Revision != int(want) {
+			t.Errorf("%d: Want n=%v at revision %v, Got n=%v at revision %d", i, want, want, got, revisions[i].Revision)
+		}
+	}
+
+	rev, err := c.GetRevision(ctx, "a", 3)
+	if err != nil || rev.Fields["n"].Value != 3.0 {
+		t.Errorf("Want revision 3, Got %v (%v)", rev, err)
+	}
+
+	if _, err := c.GetRevision(ctx, "a", 1); errors.GetKind(err) != errors.ENotFound {
+		t.Errorf("Expected revision 1 to be discarded, Got %v", err)
+	}
+
+	c.Delete(ctx, "a")
+	c.Set(ctx, "a", Fields{"n": 5.0})
+
+	doc, _ = c.Get(ctx, "a")
+	if doc.Revision != 5 {
+		t.Errorf("Expected revisions to continue after delete, Want=5 Got=%d", doc.Revision)
+	}
+
+	// Without history, the tombstone holds the last revision
+	c = newTestCollection(t, nil)
+	c.Set(ctx, "a", Fields{"n": 1.0})
+	c.Update(ctx, "a", Fields{"n": 2.0})
+	c.Delete(ctx, "a")
+
+	if ok, _ := c.repo.Exists(historyID("a")); ok {
+		t.Errorf("Expected no history to be written")
+	}
+
+	c.Set(ctx, "a", Fields{"n": 3.0})
+
+	doc, _ = c.Get(ctx, "a")
+	if doc.Revision != 3 {
+		t.Errorf("Expected revisions to continue after delete without history, Want=3 Got=%d", doc.Revision)
+	}
+
+	c.Delete(ctx, "a")
+	c.BulkLoad(ctx, &sliceIterator{docs: []types.Document{types.NewDoc("a").Set(Fields{"n": 4.0})}, i: -1})
+
+	doc, _ = c.Get(ctx, "a")
+	if doc.Revision != 4 {
+		t.Errorf("Expected revisions to continue after bulk load, Want=4 Got=%d", doc.Revision)
+	}
+
+	if revisions, err := c.History(ctx, "a"); err != nil || len(revisions) != 1 {
+		t.Errorf("Want only the current revision, Got %v (%v)", revisions, err)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Expired documents are hidden", func(t *testing.T) {
+		c := newTestCollection(t, nil)
+		c.Set(ctx, "a", Fields{"n": 1.0}, types.WithTTL(-time.Second))
+		c.Set(ctx, "b", Fields{"n": 1.0}, types.WithTTL(time.Hour))
+
+		if _, err := c.Get(ctx, "a"); errors.GetKind(err) != errors.ENotFound {
+			t.Errorf("Want error with code %s, Got %v", errors.ENotFound, err)
+		}
+
+		if _, err := c.Get(ctx, "b"); err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+
+		if res, _ := c.GetLast(ctx, 10, types.ListOptions{}); len(res.Documents) != 1 || res.Documents[0].Key != "b" {
+			t.Errorf("Expected only b to be listed, Got %v", res)
+		}
+
+		if err := c.Set(ctx, "a", Fields{"n": 2.0}); err != nil {
+			t.Errorf("Expected expired key to be replaced, Got %s", err)
+		}
+	})
+
+	t.Run("Reap", func(t *testing.T) {
+		c := newTestCollection(t, nil)
+		c.Set(ctx, "a", Fields{"n": 1.0}, types.WithTTL(-time.Second))
+		c.Set(ctx, "b", Fields{"n": 1.0})
+
+		n, err := c.Reap(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n != 1 {
+			t.Errorf("Want 1 reaped document, Got %d", n)
+		}
+
+		if _, err := c.Index.Get(ctx, "a"); errors.GetKind(err) != errors.ENotFound {
+			t.Errorf("Expected a to be removed from the index, Got %v", err)
+		}
+
+		if c.Blocks.Docs != 1 || c.Index.Records != 1 {
+			t.Errorf("Want 1 doc and 1 record, Got %d and %d", c.Blocks.Docs, c.Index.Records)
+		}
+	})
+
+	t.Run("Store reaps collections that are not loaded", func(t *testing.T) {
+		dir := t.TempDir()
+		s := New(&Config{BaseDir: dir})
+		c, _ := s.Collection("sessions")
+		if err := c.Create(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+		c.Set(ctx, "a", Fields{"n": 1.0}, types.WithTTL(-time.Second))
+		c.Set(ctx, "b", Fields{"n": 1.0})
+
+		// A restarted store has not loaded the collection
+		s = New(&Config{BaseDir: dir})
+		if err := s.Reap(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		sc, err := s.collection("sessions")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := sc.Index.Get(ctx, "a"); errors.GetKind(err) != errors.ENotFound {
+			t.Errorf("Expected a to be removed from the index, Got %v", err)
+		}
+	})
+
+	t.Run("Default TTL", func(t *testing.T) {
+		repo, _ := repository.NewMockRepo()
+		c := newCollection("test", repo)
+		c.Create(ctx, nil, types.WithDefaultTTL(time.Hour))
+
+		c.Set(ctx, "a", Fields{"n": 1.0})
+		c.Set(ctx, "b", Fields{"n": 1.0}, types.WithTTL(time.Minute))
+
+		a, _ := c.Get(ctx, "a")
+		if d := a.ExpiresAt.Sub(a.LastModified); d != time.Hour {
+			t.Errorf("Want a to expire after 1h, Got %s", d)
+		}
+
+		b, _ := c.Get(ctx, "b")
+		if d := time.Until(b.ExpiresAt); d > time.Minute {
+			t.Errorf("Want b to expire within 1m, Got %s", d)
+		}
+	})
+}
+
+func TestPagination(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCollection(t, nil)
+
+	// Inserted in the order c, a, e, b, d
+	for _, k := range []string{"c", "a", "e", "b", "d"} {
+		c.Set(ctx, k, Fields{"n": 1.0})
+	}
+	c.Delete(ctx, "e")
+
+	keys := func(p *types.Page) []string {
+		var out []string
+		for _, doc := range p.Documents {
+			out = append(out, doc.Key)
+		}
+		return out
+	}
+
+	for i, test := range []struct {
+		first   bool
+		opts    types.ListOptions
+		want    []string
+		hasMore bool
+	}{
+		{true, types.ListOptions{}, []string{"c", "a"}, true},
+		{true, types.ListOptions{Skip: 2}, []string{"b", "d"}, false},
+		{false, types.ListOptions{Skip: 1}, []string{"b", "a"}, true},
+		{true, types.ListOptions{ByKey: true}, []string{"a", "b"}, true},
+		{true, types.ListOptions{ByKey: true, Skip: 2}, []string{"c", "d"}, false},
+		{false, types.ListOptions{ByKey: true, Skip: 3}, []string{"a"}, false},
+	} {
+		get := c.GetLast
+		if test.first {
+			get = c.GetFirst
+		}
+
+		page, err := get(ctx, 2, test.opts)
+		if err != nil {
+			t.Fatalf("%d: Unexpected error: %s", i, err)
+		}
+
+		if got := keys(page); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%d: Want %v Got %v", i, test.want, got)
+		}
+
+		if page.HasMore != test.hasMore {
+			t.Errorf("%d: HasMore, Want=%v Got=%v", i, test.hasMore, page.HasMore)
+		}
+	}
+
+	t.Run("Cursor", func(t *testing.T) {
+		for _, opts := range []types.ListOptions{{}, {ByKey: true}} {
+			page, _ := c.GetFirst(ctx, 4, opts)
+			if page.HasMore {
+				t.Errorf("ByKey=%v: Expected no more documents", opts.ByKey)
+			}
+
+			// The cursor remains valid when documents are
+			// inserted into the head block
+			c.Upsert(ctx, "f", Fields{"n": 1.0})
+
+			opts.After = page.Cursor
+			next, err := c.GetFirst(ctx, 4, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := keys(next); !reflect.DeepEqual(got, []string{"f"}) {
+				t.Errorf("ByKey=%v: Want [f] Got %v", opts.ByKey, got)
+			}
+
+			c.Delete(ctx, "f")
+		}
+	})
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		page, _ := c.GetFirst(ctx, 1, types.ListOptions{})
+
+		_, err := c.GetFirst(ctx, 1, types.ListOptions{After: page.Cursor, ByKey: true})
+		if errors.GetKind(err) != errors.EBadRequest {
+			t.Errorf("Want error with code %s, Got %v", errors.EBadRequest, err)
+		}
+	})
+}
+
+func TestAggregate(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCollection(t, nil)
+
+	c.Set(ctx, "a", Fields{"country": "NO", "age": 30.0, "alive": true})
+	c.Set(ctx, "b", Fields{"country": "NO", "age": 40.0, "alive": false})
+	c.Set(ctx, "c", Fields{"country": "SE", "age": 20.0, "alive": true})
+	c.Set(ctx, "d", Fields{"age": 50.0, "alive": true})
+	c.Set(ctx, "e", Fields{"country": "SE", "age": 90.0, "alive": true}, types.WithTTL(-time.Second))
+
+	alive := types.Filter{{
+		Path:  []string{"alive"},
+		Op:    types.Eq,
+		Value: types.Field{Type: types.Boolean, Value: true},
+	}}
+
+	n, err := c.Count(ctx, alive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 3 {
+		t.Errorf("Count, Want=%d Got=%d", 3, n)
+	}
+
+	rows, err := c.Aggregate(ctx, types.AggregateQuery{
+		Aggregates: []types.Aggregate{
+			{Func: types.Avg, Path: []string{"age"}},
+			{Func: types.Count},
+		},
+		GroupBy: [][]string{{"country"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []map[string]interface{}{
+		{"country": "NO", "AVG(age)": json.Number("35"), "COUNT(*)": 2},
+		{"country": "SE", "AVG(age)": json.Number("20"), "COUNT(*)": 1},
+		{"country": nil, "AVG(age)": json.Number("50"), "COUNT(*)": 1},
+	}
+
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("Want %v Got %v", want, rows)
+	}
+
+	_, err = c.Aggregate(ctx, types.AggregateQuery{
+		Aggregates: []types.Aggregate{{Func: types.Sum, Path: []string{"country"}}},
+	})
+	if errors.GetKind(err) != errors.EBadRequest {
+		t.Errorf("Want error with code %s, Got %v", errors.EBadRequest, err)
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCollection(t, nil)
+
+	c.Set(ctx, "a", Fields{"country": "NO", "age": 30.0})
+	c.Set(ctx, "b", Fields{"country": "SE", "age": 20.0})
+	c.Set(ctx, "c", Fields{"country": "NO", "age": 40.0})
+	c.Set(ctx, "d", Fields{"age": 10.0})
+	c.Set(ctx, "e", Fields{"country": "SE", "age": 50.0})
+
+	defer func(n int) { sortRunSize = n }(sortRunSize)
+	sortRunSize = 2
+
+	spilled := func() int {
+		files, _ := filepath.Glob(filepath.Join(os.TempDir(), "keylime-sort-*"))
+		return len(files)
+	}
+	before := spilled()
+
+	order := types.OrderBy{{Path: []string{"country"}}, {Path: []string{"age"}, Desc: true}}
+
+	for i, test := range []struct {
+		first   bool
+		skip    int
+		want    []string
+		hasMore bool
+	}{
+		{true, 0, []string{"c", "a", "e"}, true},
+		{true, 3, []string{"b", "d"}, false},
+		{false, 0, []string{"d", "b", "e"}, true},
+	} {
+		get := c.GetLast
+		if test.first {
+			get = c.GetFirst
+		}
+
+		page, err := get(ctx, 3, types.ListOptions{OrderBy: order, Skip: test.skip})
+		if err != nil {
+			t.Fatalf("%d: Unexpected error: %s", i, err)
+		}
+
+		var got []string
+		for _, doc := range page.Documents {
+			got = append(got, doc.Key)
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%d: Want %v Got %v", i, test.want, got)
+		}
+
+		if page.HasMore != test.hasMore {
+			t.Errorf("%d: HasMore, Want=%v Got=%v", i, test.hasMore, page.HasMore)
+		}
+	}
+
+	if after := spilled(); after != before {
+		t.Errorf("Expected spilled runs to be removed, found %d files", after-before)
+	}
+
+	_, err := c.GetFirst(ctx, 3, types.ListOptions{OrderBy: order, After: "x"})
+	if errors.GetKind(err) != errors.EBadRequest {
+		t.Errorf("Want error with code %s, Got %v", errors.EBadRequest, err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	schema, _ := types.NewSchemaBuilder().
+		AddField("age", types.Number).
+		AddField("nickname", types.String, types.Optional).
+		Build()
+
+	c := newTestCollection(t, &schema)
+	c.Set(ctx, "a", Fields{"age": 1.0, "nickname": "x"})
+
+	err := c.Apply(ctx, "a", []types.UpdateOp{
+		{Kind: types.IncOp, Path: []string{"age"}, Value: 2.0},
+		{Kind: types.UnsetOp, Path: []string{"nickname"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, _ := c.Get(ctx, "a")
+	if got := doc.Fields["age"].Value; got != json.Number("3") {
+		t.Errorf("Want age=3, Got %v", got)
+	}
+
+	if _, ok := doc.Fields["nickname"]; ok {
+		t.Errorf("Expected nickname to be unset")
+	}
+
+	for i, ops := range [][]types.UpdateOp{
+		// The schema requires age
+		{
+			{Kind: types.IncOp, Path: []string{"age"}, Value: 1.0},
+			{Kind: types.UnsetOp, Path: []string{"age"}},
+		},
+		// nickname is not an Array
+		{
+			{Kind: types.SetOp, Path: []string{"nickname"}, Value: "y"},
+			{Kind: types.PushOp, Path: []string{"nickname"}, Value: "z"},
+		},
+	} {
+		if err := c.Apply(ctx, "a", ops); errors.GetKind(err) != errors.EBadRequest {
+			t.Errorf("%d: Want error with code %s, Got %v", i, errors.EBadRequest, err)
+		}
+	}
+
+	after, _ := c.Get(ctx, "a")
+	if after.Version != doc.Version || after.Revision != doc.Revision {
+		t.Errorf("Expected rejected updates to leave the document unchanged")
+	}
+}
+
+func TestValidation(t *testing.T) {
+	ctx := context.Background()
+	schema, _ := types.NewSchemaBuilder().AddField("age", types.Number).Build()
+	c := newTestCollection(t, &schema)
+
+	if err := c.Set(ctx, "a", Fields{"age": 1.0}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, write := range map[string]func() error{
+		"Set":    func() error { return c.Set(ctx, "b", Fields{"age": "x"}) },
+		"Upsert": func() error { return c.Upsert(ctx, "a", Fields{"age": "x"}) },
+		"Update": func() error { return c.Update(ctx, "a", map[string]interface{}{"age": "x"}) },
+		"Apply": func() error {
+			return c.Apply(ctx, "a", []types.UpdateOp{{Kind: types.SetOp, Path: []string{"age"}, Value: "x"}})
+		},
+		"SetMany": func() error {
+			return c.SetMany(ctx, map[string]Fields{"b": {"age": "x"}}, types.Partial)
+		},
+		"BulkLoad": func() error {
+			_, err := c.BulkLoad(ctx, &sliceIterator{docs: []types.Document{types.NewDoc("b").Set(Fields{"age": "x"})}, i: -1})
+			return err
+		},
+	} {
+		err := write()
+		if errors.GetKind(err) != errors.EBadRequest {
+			t.Errorf("%s: Want error with code %s, Got %v", name, errors.EBadRequest, err)
+		}
+
+		// Batch paths report the rejected documents by key
+		var be types.BatchError
+		if errors.As(err, &be) {
+			err = be["b"]
+		}
+
+		var ve types.ValidationError
+		if !errors.As(err, &ve) || ve["age"] == nil {
+			t.Errorf("%s: Expected a ValidationError for age, Got %v", name, err)
+		}
+	}
+
+	if c.Blocks.Docs != 1 || c.Index.Records != 1 {
+		t.Errorf("Expected invalid documents to be rejected, Got %d docs and %d records", c.Blocks.Docs, c.Index.Records)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	ctx := context.Background()
+	collections := make(map[string]*Collection)
+
+	create := func(name string, s *types.Schema) *Collection {
+		repo, _ := repository.NewMockRepo()
+		c := newCollection(name, repo)
+		c.resolve = func(name string) (*Collection, error) {
+			if rc, ok := collections[name]; ok {
+				return rc, nil
+			}
+			return nil, errors.Wrap("resolve", errors.ENotFound, fmt.Errorf("Collection %s does not exist", name))
+		}
+
+		if err := c.Create(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+
+		collections[name] = c
+		return c
+	}
+
+	refSchema := func(policy types.RefPolicy, opts ...types.SchemaFieldOption) *types.Schema {
+		opts = append(opts, types.WithRef("users", policy))
+		s, err := types.NewSchemaBuilder().AddField("user", types.String, opts...).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &s
+	}
+
+	users := create("users", nil)
+	orders := create("orders", refSchema(types.Restrict))
+	sessions := create("sessions", refSchema(types.Cascade))
+	posts := create("posts", refSchema(types.SetNull, types.Optional))
+
+	for _, k := range []string{"u1", "u2", "u3"} {
+		if err := users.Set(ctx, k, Fields{"name": k}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(users.Referrers) != 3 {
+		t.Fatalf("Want 3 referrers, Got %v", users.Referrers)
+	}
+
+	t.Run("Validate on write", func(t *testing.T) {
+		err := orders.Set(ctx, "o0", Fields{"user": "u9"})
+
+		var ve types.ValidationError
+		if errors.GetKind(err) != errors.EBadRequest || !errors.As(err, &ve) || ve["user"] == nil {
+			t.Errorf("Want ValidationError for user, Got %v", err)
+		}
+
+		if err := orders.Set(ctx, "o1", Fields{"user": "u1"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Expand", func(t *testing.T) {
+		doc, err := orders.Get(ctx, "o1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expanded, err := orders.Expand(ctx, *doc, "user")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := map[string]interface{}{"name": "u1"}
+		if got := expanded.Fields["user"].Value; !reflect.DeepEqual(got, want) {
+			t.Errorf("Want %v, Got %v", want, got)
+		}
+
+		if doc.Fields["user"].Value != "u1" {
+			t.Errorf("Expected the original document to be unchanged, Got %v", doc)
+		}
+	})
+
+	t.Run("Restrict", func(t *testing.T) {
+		if err := users.Delete(ctx, "u1"); errors.GetKind(err) != errors.EConflict {
+			t.Errorf("Want error with code %s, Got %v", errors.EConflict, err)
+		}
+
+		if _, err := users.Get(ctx, "u1"); err != nil {
+			t.Errorf("Expected u1 to remain, Got %v", err)
+		}
+	})
+
+	t.Run("Cascade", func(t *testing.T) {
+		if err := sessions.Set(ctx, "s1", Fields{"user": "u2"}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := users.Delete(ctx, "u2"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := sessions.Get(ctx, "s1"); errors.GetKind(err) != errors.ENotFound {
+			t.Errorf("Expected s1 to be deleted, Got %v", err)
+		}
+	})
+
+	t.Run("Set null", func(t *testing.T) {
+		if err := posts.Set(ctx, "p1", Fields{"user": "u3"}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := users.Delete(ctx, "u3"); err != nil {
+			t.Fatal(err)
+		}
+
+		doc, err := posts.Get(ctx, "p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := doc.Fields["user"]; ok {
+			t.Errorf("Expected user to be removed, Got %v", doc)
+		}
+	})
+
+	t.Run("Chained cascade", func(t *testing.T) {
+		s, err := types.NewSchemaBuilder().AddField("session", types.String, types.WithRef("sessions", types.Cascade)).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		events := create("events", &s)
+
+		if err := users.Set(ctx, "u4", Fields{"name": "u4"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := sessions.Set(ctx, "s2", Fields{"user": "u4"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := events.Set(ctx, "e1", Fields{"session": "s2"}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := users.Delete(ctx, "u4"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := sessions.Get(ctx, "s2"); errors.GetKind(err) != errors.ENotFound {
+			t.Errorf("Expected s2 to be deleted, Got %v", err)
+		}
+		if _, err := events.Get(ctx, "e1"); errors.GetKind(err) != errors.ENotFound {
+			t.Errorf("Expected e1 to be deleted, Got %v", err)
+		}
+	})
+}
+
+func TestUnique(t *testing.T) {
+	ctx := context.Background()
+	schema, _ := types.NewSchemaBuilder().AddField("email", types.String, types.Unique, types.Optional).Build()
+	c := newTestCollection(t, &schema)
+
+	conflict := func(name string, err error) {
+		t.Helper()
+
+		var ve types.ValidationError
+		if errors.GetKind(err) != errors.EConflict || !errors.As(err, &ve) || ve["email"] == nil {
+			t.Errorf("%s: Want conflict on email, Got %v", name, err)
+		}
+	}
+
+	if err := c.Set(ctx, "a", Fields{"email": "x"}); err != nil {
+		t.Fatal(err)
+	}
+
+	conflict("Set", c.Set(ctx, "b", Fields{"email": "x"}))
+
+	if err := c.Set(ctx, "b", Fields{"email": "y"}); err != nil {
+		t.Fatal(err)
+	}
+
+	conflict("Update", c.Update(ctx, "b", map[string]interface{}{"email": "x"}))
+	conflict("Upsert", c.Upsert(ctx, "b", Fields{"email": "x"}))
+
+	// Writing the same value to the same document is allowed
+	if err := c.Upsert(ctx, "a", Fields{"email": "x"}); err != nil {
+		t.Errorf("Want nil error, Got %v", err)
+	}
+
+	// Values are released when they change or the document is
+	// deleted
+	if err := c.Update(ctx, "a", map[string]interface{}{"email": "z"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Set(ctx, "c", Fields{"email": "x"}); err != nil {
+		t.Errorf("Want nil error, Got %v", err)
+	}
+
+	if err := c.Delete(ctx, "c"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Set(ctx, "d", Fields{"email": "x"}); err != nil {
+		t.Errorf("Want nil error, Got %v", err)
+	}
+
+	// Documents without the field do not conflict
+	if err := c.SetMany(ctx, map[string]Fields{"e": {}, "f": {}}, types.AllOrNothing); err != nil {
+		t.Errorf("Want nil error, Got %v", err)
+	}
+
+	err := c.SetMany(ctx, map[string]Fields{"g": {"email": "w"}, "h": {"email": "w"}}, types.Partial)
+	var be types.BatchError
+	if !errors.As(err, &be) || len(be) != 1 {
+		t.Fatalf("Expected one document to be rejected, Got %v", err)
+	}
+	conflict("SetMany", be["h"])
+}
+
+func TestComputedFields(t *testing.T) {
+	ctx := types.WithClient(context.Background(), "client1")
+	schema, _ := types.NewSchemaBuilder().
+		AddField("first", types.String).
+		AddField("last", types.String).
+		AddField("createdBy", types.String, types.WithComputed(types.Var("client"))).
+		AddField("fullName", types.String, types.WithComputed(types.Plus(types.FieldRef("first"), types.Lit(" "), types.FieldRef("last")))).
+		Build()
+	c := newTestCollection(t, &schema)
+
+	// Values sent for computed fields are ignored
+	if err := c.Set(ctx, "a", Fields{"first": "Ola", "last": "Nordmann", "createdBy": "x"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Update(context.Background(), "a", map[string]interface{}{"first": "Kari", "createdBy": "y"}); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := c.Get(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := doc.Fields["fullName"].Value; got != "Kari Nordmann" {
+		t.Errorf("Want fullName Kari Nordmann, Got %v", got)
+	}
+
+	if got := doc.Fields["createdBy"].Value; got != "client1" {
+		t.Errorf("Want createdBy client1, Got %v", got)
+	}
+
+	other := types.WithClient(context.Background(), "client2")
+	if err := c.Upsert(other, "a", Fields{"first": "Ola", "last": "Nordmann", "createdBy": "z"}); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, _ = c.Get(ctx, "a")
+	if got := doc.Fields["createdBy"].Value; got != "client1" {
+		t.Errorf("Want createdBy to be kept by Upsert, Got %v", got)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+
+	for strategy, pattern := range map[types.KeyStrategy]string{
+		types.UUIDKeys:   `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`,
+		types.UUIDv7Keys: `^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`,
+		types.ULIDKeys:   `^[0-9A-HJKMNP-TV-Z]{26}$`,
+	} {
+		repo, _ := repository.NewMockRepo()
+		c := newCollection("test", repo)
+		if err := c.Create(ctx, nil, types.WithKeys(strategy)); err != nil {
+			t.Fatal(err)
+		}
+
+		var prev string
+		for i := 0; i < 3; i++ {
+			k, err := c.Insert(ctx, Fields{"i": i})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !regexp.MustCompile(pattern).MatchString(k) {
+				t.Errorf("%s: Key %s does not match %s", strategy, k, pattern)
+			}
+
+			if _, err := c.Get(ctx, k); err != nil {
+				t.Errorf("%s: %v", strategy, err)
+			}
+
+			// The first 10 characters hold the time in both ULIDs
+			// and UUIDv7s
+			if strategy != types.UUIDKeys && k[:10] < prev {
+				t.Errorf("%s: Want keys in time order, Got %s after %s", strategy, k, prev)
+			}
+			prev = k[:10]
+		}
+	}
+
+	t.Run("Sequence", func(t *testing.T) {
+		repo, _ := repository.NewMockRepo()
+		c := newCollection("test", repo)
+		if err := c.Create(ctx, nil, types.WithKeys(types.SequenceKeys)); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := c.Set(ctx, sequenceKey(2), Fields{}); err != nil {
+			t.Fatal(err)
+		}
+
+		var keys []string
+		for i := 0; i < 2; i++ {
+			k, err := c.Insert(ctx, Fields{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys = append(keys, k)
+		}
+
+		if want := []string{"00000000000000000001", "00000000000000000003"}; !reflect.DeepEqual(keys, want) {
+			t.Errorf("Want keys %v, Got %v", want, keys)
+		}
+
+		if c.Sequence != 3 {
+			t.Errorf("Want sequence 3, Got %d", c.Sequence)
+		}
+
+		// Keys sort in the order of the sequence
+		for i := 0; i < 10; i++ {
+			c.Insert(ctx, Fields{})
+		}
+
+		page, err := c.GetFirst(ctx, 20, types.ListOptions{ByKey: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, doc := range page.Documents {
+			if want := sequenceKey(int64(i + 1)); doc.Key != want {
+				t.Errorf("%d: Want key %s, Got %s", i, want, doc.Key)
+			}
+		}
+	})
+
+	t.Run("ULIDs within a millisecond", func(t *testing.T) {
+		now := time.Now()
+		prev, _ := newULID(now)
+		for i := 0; i < 100; i++ {
+			k, err := newULID(now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if k <= prev {
+				t.Fatalf("Want ULIDs in the order they were generated, Got %s after %s", k, prev)
+			}
+			prev = k
+		}
+	})
+
+	t.Run("Unknown strategy", func(t *testing.T) {
+		repo, _ := repository.NewMockRepo()
+		c := newCollection("test", repo)
+		if err := c.Create(ctx, nil, types.WithKeys("random")); errors.GetKind(err) != errors.EBadRequest {
+			t.Errorf("Want error with code %s, Got %v", errors.EBadRequest, err)
+		}
+	})
+}
+
+func TestInfo(t *testing.T) {
+	ctx := context.Background()
+	s := New(&Config{BaseDir: t.TempDir()})
+
+	schema, verr := types.NewSchemaBuilder().
+		AddField("email", types.String, types.Unique).
+		Build()
+	if verr != nil {
+		t.Fatal(verr)
+	}
+
+	c, err := s.Collection("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Info(ctx); errors.GetKind(err) != errors.ENotFound {
+		t.Errorf("Want ENotFound before the collection is created, Got %v", err)
+	}
+
+	if err := c.Create(ctx, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		k := fmt.Sprintf("user%d", i)
+		if err := c.Set(ctx, k, Fields{"email": k + "@example.com"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.Delete(ctx, "user0"); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := c.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Docs != 2 || info.Deleted != 1 {
+		t.Errorf("Want 2 docs and 1 deleted, Got %d and %d", info.Docs, info.Deleted)
+	}
+
+	if info.CreatedAt.IsZero() || len(info.Schema) != 1 {
+		t.Errorf("Want the creation time and schema, Got %+v", info)
+	}
+
+	if info.Index.Nodes != 1 || info.Index.Height != 0 || info.Index.AverageFill <= 0 {
+		t.Errorf("Unexpected index stats %+v", info.Index)
+	}
+
+	if info.Unique["email"].Records != 2 {
+		t.Errorf("Want 2 records in the unique index, Got %+v", info.Unique)
+	}
+
+	if info.Blocks.Blocks != 1 || info.Blocks.FillFactor != 3.0/200 {
+		t.Errorf("Unexpected block list stats %+v", info.Blocks)
+	}
+
+	if info.Bytes["history"] != 0 {
+		t.Errorf("Want no history, Got %d bytes", info.Bytes["history"])
+	}
+
+	for _, scope := range []string{"total", "header", "index", "unique", "blocks", "tombstones"} {
+		if info.Bytes[scope] <= 0 {
+			t.Errorf("Want the size of %s on disk, Got %d", scope, info.Bytes[scope])
+		}
+	}
+
+	posts, err := s.Collection("posts")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := posts.Create(ctx, nil, types.WithHistory(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := posts.Set(ctx, "a", Fields{"n": 1.0}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := posts.Update(ctx, "a", Fields{"n": 2.0}); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err = posts.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := info.Bytes["history"]; got <= 0 || got >= info.Bytes["total"] {
+		t.Errorf("Want the size of the history on disk, Got %d of %d bytes", got, info.Bytes["total"])
+	}
+}
+
+func TestDrop(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s := New(&Config{BaseDir: dir})
+
+	schema, verr := types.NewSchemaBuilder().
+		AddField("email", types.String, types.Unique).
+		Build()
+	if verr != nil {
+		t.Fatal(verr)
+	}
+
+	for _, name := range []string{"users", "teams"} {
+		c, _ := s.Collection(name)
+		if err := c.Create(ctx, &schema, types.WithHistory(2)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, _ := s.Collection("users")
+	for i := 0; i < 200; i++ {
+		k := fmt.Sprintf("user%d", i)
+		if err := c.Set(ctx, k, Fields{"email": k}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.Update(ctx, "user1", Fields{"email": "other"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// An operation may be in progress while the collection is
+	// dropped. Operations fail with ENotFound afterwards.
+	started, done := make(chan bool), make(chan error)
+	go func() {
+		for i := 0; ; i++ {
+			k := fmt.Sprintf("w%d", i)
+			if err := c.Set(ctx, k, Fields{"email": k}); err != nil {
+				done <- err
+				return
+			}
+
+			if i == 0 {
+				close(started)
+			}
+		}
+	}()
+
+	<-started
+	if err := s.Drop(ctx, "users"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; errors.GetKind(err) != errors.ENotFound {
+		t.Errorf("Want ENotFound after the collection was dropped, Got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "users")); !os.IsNotExist(err) {
+		t.Errorf("Want the scope of the collection to be removed, Got %v", err)
+	}
+
+	if names, err := s.Collections(ctx); err != nil || !reflect.DeepEqual(names, []string{"teams"}) {
+		t.Errorf("Want [teams], Got %v (%v)", names, err)
+	}
+
+	if err := s.Drop(ctx, "users"); errors.GetKind(err) != errors.ENotFound {
+		t.Errorf("Want ENotFound, Got %v", err)
+	}
+
+	// The name can be used again
+	c, _ = s.Collection("users")
+	if err := c.Create(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Get(ctx, "user1"); errors.GetKind(err) != errors.ENotFound {
+		t.Errorf("Want ENotFound, Got %v", err)
+	}
+
+	t.Run("Referenced collections", func(t *testing.T) {
+		refs, _ := types.NewSchemaBuilder().
+			AddField("team", types.String, types.WithRef("teams", types.Cascade)).
+			Build()
+
+		members, _ := s.Collection("members")
+		if err := members.Create(ctx, &refs); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.Drop(ctx, "teams"); errors.GetKind(err) != errors.EConflict {
+			t.Errorf("Want EConflict, Got %v", err)
+		}
+
+		if err := s.Rename(ctx, "teams", "groups"); errors.GetKind(err) != errors.EConflict {
+			t.Errorf("Want EConflict, Got %v", err)
+		}
+
+		// Dropping the referring collection removes its
+		// referrers from the referenced one
+		if err := s.Drop(ctx, "members"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.Drop(ctx, "teams"); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestRename(t *testing.T) {
+	ctx := context.Background()
+	s := New(&Config{BaseDir: t.TempDir()})
+
+	for _, name := range []string{"users", "other"} {
+		c, _ := s.Collection(name)
+		if err := c.Create(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, _ := s.Collection("users")
+	if err := c.Set(ctx, "user1", Fields{"name": "Ola"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Rename(ctx, "users", "other"); errors.GetKind(err) != errors.EConflict {
+		t.Errorf("Want EConflict, Got %v", err)
+	}
+
+	if err := s.Rename(ctx, "users", "people"); err != nil {
+		t.Fatal(err)
+	}
+
+	if names, _ := s.Collections(ctx); !reflect.DeepEqual(names, []string{"other", "people"}) {
+		t.Errorf("Want [other people], Got %v", names)
+	}
+
+	if err := s.Drop(ctx, "users"); errors.GetKind(err) != errors.ENotFound {
+		t.Errorf("Want ENotFound, Got %v", err)
+	}
+
+	// The renamed collection is read back from disk
+	people, err := New(&Config{BaseDir: s.baseDir}).Collection("people")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := people.Get(ctx, "user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Fields["name"].Value != "Ola" {
+		t.Errorf("Want name Ola, Got %v", doc.Fields["name"].Value)
+	}
+
+	if err := people.Set(ctx, "user2", Fields{"name": "Kari"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMissingCollection(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Operations fail with ENotFound", func(t *testing.T) {
+		s := New(&Config{BaseDir: t.TempDir()})
+		c, err := s.Collection("missing")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := c.Get(ctx, "k"); !errors.Is(err, errors.ErrCollectionNotFound) || errors.GetKind(err) != errors.ENotFound {
+			t.Errorf("Want a collection ENotFound error, Got %v", err)
+		}
+
+		if err := c.Set(ctx, "k", Fields{"a": 1}); !errors.Is(err, errors.ErrCollectionNotFound) {
+			t.Errorf("Want a collection ENotFound error, Got %v", err)
+		}
+
+		if err := c.Create(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := c.Create(ctx, nil); !errors.Is(err, errors.ErrCollectionExists) || errors.GetKind(err) != errors.EConflict {
+			t.Errorf("Want a collection EConflict error, Got %v", err)
+		}
+
+		// An existing collection is loaded as created
+		c, _ = s.Collection("missing")
+		if err := c.Create(ctx, nil); !errors.Is(err, errors.ErrCollectionExists) {
+			t.Errorf("Want a collection EConflict error, Got %v", err)
+		}
+
+		if err := c.Set(ctx, "k", Fields{"a": 1}); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("Concurrent creations", func(t *testing.T) {
+		s := New(&Config{BaseDir: t.TempDir()})
+
+		errs := make(chan error)
+		for i := 0; i < 4; i++ {
+			go func() {
+				c, _ := s.Collection("events")
+				errs <- c.Create(ctx, nil)
+			}()
+		}
+
+		created := 0
+		for i := 0; i < 4; i++ {
+			err := <-errs
+			if err == nil {
+				created++
+			} else if !errors.Is(err, errors.ErrCollectionExists) {
+				t.Errorf("Want a collection EConflict error, Got %v", err)
+			}
+		}
+
+		if created != 1 {
+			t.Errorf("Want the collection to be created once, Got %d", created)
+		}
+	})
+
+	t.Run("AutoCreate", func(t *testing.T) {
+		s := New(&Config{BaseDir: t.TempDir(), AutoCreate: true})
+
+		c, _ := s.Collection("events")
+		if _, err := c.Get(ctx, "k"); !errors.Is(err, errors.ErrCollectionNotFound) {
+			t.Errorf("Want reads not to create the collection, Got %v", err)
+		}
+
+		// Concurrent first writes create the collection once
+		errs := make(chan error)
+		for i := 0; i < 4; i++ {
+			go func(i int) {
+				c, _ := s.Collection("events")
+				errs <- c.Set(ctx, fmt.Sprintf("k%d", i), Fields{"i": i})
+			}(i)
+		}
+
+		for i := 0; i < 4; i++ {
+			if err := <-errs; err != nil {
+				t.Error(err)
+			}
+		}
+
+		c, _ = s.Collection("events")
+		if n, err := c.Count(ctx, nil); err != nil || n != 4 {
+			t.Errorf("Want 4 documents, Got %d (%v)", n, err)
+		}
+
+		if names, _ := s.Collections(ctx); !reflect.DeepEqual(names, []string{"events"}) {
+			t.Errorf("Want [events], Got %v", names)
+		}
+	})
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	s := New(&Config{BaseDir: t.TempDir()})
+
+	c, _ := s.Collection("counters")
+	if err := c.Create(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Set(ctx, "total", Fields{"n": 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Writes from separate connections do not interleave
+	errs := make(chan error)
+	for i := 0; i < 8; i++ {
+		go func(i int) {
+			for j := 0; j < 20; j++ {
+				if err := c.Set(ctx, fmt.Sprintf("k%d-%d", i, j), Fields{"i": i}); err != nil {
+					errs <- err
+					return
+				}
+
+				err := c.Apply(ctx, "total", []types.UpdateOp{{Kind: types.IncOp, Path: []string{"n"}, Value: json.Number("1")}})
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+
+			errs <- nil
+		}(i)
+	}
+
+	for i := 0; i < 8; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+
+	if n, err := c.Count(ctx, nil); err != nil || n != 161 {
+		t.Errorf("Want 161 documents, Got %d (%v)", n, err)
+	}
+
+	doc, err := c.Get(ctx, "total")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := doc.Fields["n"].Value; got != json.Number("160") {
+		t.Errorf("Want n = 160, Got %v", got)
+	}
+}
@@ -0,0 +1,317 @@
+package store
+
+import (
+	"context"
+	"encoding/base32"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/namvu9/keylime/src/index"
+	"github.com/namvu9/keylime/src/types"
+)
+
+// A Cursor iterates lazily over the live documents in a
+// block list, either in insertion order (ascending) or in
+// reverse insertion order. Blocks are loaded as the cursor
+// reaches them.
+type Cursor struct {
+	ctx context.Context
+	bl  *Blocklist
+	asc bool
+	now time.Time
+
+	block *Block
+	pos   int // Position of the next document in block
+
+	// Position of the last document visited by the cursor
+	curBlock ID
+	cur      int
+
+	doc types.Document
+	err error
+}
+
+// Next advances the cursor to the next live document and
+// reports whether there is one. It returns false if the
+// context is cancelled or a block cannot be loaded, in
+// which case Err returns the cause.
+func (c *Cursor) Next() bool {
+	for c.block != nil {
+		if err := c.ctx.Err(); err != nil {
+			c.err = err
+			return false
+		}
+
+		if c.pos < 0 || c.pos >= len(c.block.Docs) {
+			next := c.block.Next
+			if c.asc {
+				next = c.block.Prev
+			}
+
+			if next == "" {
+				return false
+			}
+
+			block, err := c.bl.GetBlock(next)
+			if err != nil {
+				c.err = err
+				return false
+			}
+
+			c.setBlock(block)
+			continue
+		}
+
+		c.curBlock, c.cur = c.block.Identifier, c.pos
+		doc := c.block.Docs[c.pos]
+
+		if c.asc {
+			c.pos++
+		} else {
+			c.pos--
+		}
+
+		if !doc.Deleted && !doc.Expired(c.now) {
+			c.doc = doc
+			return true
+		}
+	}
+
+	return false
+}
+
+// Value returns the current document
+func (c *Cursor) Value() types.Document {
+	return c.doc
+}
+
+// Err returns the error that stopped the iteration, if any
+func (c *Cursor) Err() error {
+	return c.err
+}
+
+// Token returns an opaque token for the position of the
+// current document. A cursor created with the token resumes
+// after that document. Since documents are only ever
+// appended to blocks, the token remains valid when new
+// documents are inserted.
+func (c *Cursor) Token() string {
+	if c.curBlock == "" {
+		return ""
+	}
+
+	dir := "d"
+	if c.asc {
+		dir = "a"
+	}
+
+	s := fmt.Sprintf("%s:%s:%d", dir, c.curBlock, c.cur)
+	return tokenEncoding.EncodeToString([]byte(s))
+}
+
+// setBlock positions the cursor at the first document of
+// `block` in the direction of iteration
+func (c *Cursor) setBlock(block *Block) {
+	c.block = block
+	c.pos = 0
+
+	if !c.asc {
+		c.pos = len(block.Docs) - 1
+	}
+}
+
+var tokenEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// decodeToken returns the block ID and position of the
+// document identified by a cursor token
+func decodeToken(token string, asc bool) (ID, int, error) {
+	b, err := tokenEncoding.DecodeString(token)
+	if err != nil {
+		return "", 0, fmt.Errorf("Invalid cursor: %s", token)
+	}
+
+	parts := strings.Split(string(b), ":")
+	if len(parts) != 3 {
+		return "", 0, fmt.Errorf("Invalid cursor: %s", token)
+	}
+
+	if dir := parts[0]; dir != "a" && dir != "d" {
+		return "", 0, fmt.Errorf("Invalid cursor: %s", token)
+	} else if (dir == "a") != asc {
+		return "", 0, fmt.Errorf("Cursor %s belongs to a listing in the opposite order", token)
+	}
+
+	pos, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return "", 0, fmt.Errorf("Invalid cursor: %s", token)
+	}
+
+	return ID(parts[1]), pos, nil
+}
+
+// Cursor returns a cursor over the documents in the block
+// list, starting at the tail if `asc` is true and at the
+// head otherwise. If `after` is a token obtained from
+// another cursor, iteration resumes after the document it
+// identifies.
+func (bl *Blocklist) Cursor(ctx context.Context, asc bool, after string) (*Cursor, error) {
+	c := &Cursor{
+		ctx: ctx,
+		bl:  bl,
+		asc: asc,
+		now: time.Now(),
+	}
+
+	if after == "" {
+		start := bl.Head
+		if asc {
+			start = bl.Tail
+		}
+
+		block, err := bl.GetBlock(start)
+		if err != nil {
+			return nil, err
+		}
+
+		c.setBlock(block)
+		return c, nil
+	}
+
+	id, pos, err := decodeToken(after, asc)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := bl.GetBlock(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if pos >= len(block.Docs) {
+		return nil, fmt.Errorf("Invalid cursor: %s", after)
+	}
+
+	c.block, c.pos = block, pos-1
+	if asc {
+		c.pos = pos + 1
+	}
+	c.curBlock, c.cur = id, pos
+
+	return c, nil
+}
+
+// A keyCursor iterates over the live documents in a
+// collection in key order
+type keyCursor struct {
+	c   *Collection
+	it  *index.Cursor
+	asc bool
+	now time.Time
+	key string // Key of the last document visited
+	doc types.Document
+	err error
+}
+
+// Next advances the cursor to the next live document and
+// reports whether there is one
+func (kc *keyCursor) Next() bool {
+	for kc.it.Next() {
+		ref := kc.it.Value()
+		kc.key = ref.Key
+
+		block, err := kc.c.Blocks.GetBlock(ID(ref.Value))
+		if err != nil {
+			kc.err = err
+			return false
+		}
+
+		doc, err := block.Get(ref.Key)
+		if err != nil {
+			kc.err = err
+			return false
+		}
+
+		if !doc.Expired(kc.now) {
+			kc.doc = *doc
+			return true
+		}
+	}
+
+	kc.err = kc.it.Err()
+	return false
+}
+
+// Value returns the current document
+func (kc *keyCursor) Value() types.Document {
+	return kc.doc
+}
+
+// Err returns the error that stopped the iteration, if any
+func (kc *keyCursor) Err() error {
+	return kc.err
+}
+
+// Token returns an opaque token for the key of the current
+// document. Unlike block positions, keys remain valid
+// cursors after the document is deleted.
+func (kc *keyCursor) Token() string {
+	if kc.key == "" {
+		return ""
+	}
+
+	dir := "kd"
+	if kc.asc {
+		dir = "ka"
+	}
+
+	return tokenEncoding.EncodeToString([]byte(dir + ":" + kc.key))
+}
+
+// decodeKeyToken returns the key identified by a key order
+// cursor token
+func decodeKeyToken(token string, asc bool) (string, error) {
+	b, err := tokenEncoding.DecodeString(token)
+	if err != nil {
+		return "", fmt.Errorf("Invalid cursor: %s", token)
+	}
+
+	parts := strings.SplitN(string(b), ":", 2)
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "k") {
+		return "", fmt.Errorf("Invalid cursor: %s", token)
+	}
+
+	if dir := parts[0]; (dir == "ka") != asc {
+		return "", fmt.Errorf("Cursor %s belongs to a listing in the opposite order", token)
+	}
+
+	return parts[1], nil
+}
+
+// A guardedCursor holds the guard of the collection that it
+// iterates over shared while it advances. It stops with an
+// error with code ENotFound if the collection is dropped.
+type guardedCursor struct {
+	types.Cursor
+	c   *Collection
+	err error
+}
+
+func (gc *guardedCursor) Next() bool {
+	if err := gc.c.rlock(); err != nil {
+		gc.err = err
+		return false
+	}
+	defer gc.c.runlock()
+
+	return gc.Cursor.Next()
+}
+
+func (gc *guardedCursor) Err() error {
+	if gc.err != nil {
+		return gc.err
+	}
+
+	return gc.Cursor.Err()
+}
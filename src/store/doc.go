@@ -11,4 +11,5 @@ func init() {
 	gob.Register(&Blocklist{})
 	gob.Register(&Block{})
 	gob.Register(&Collection{})
+	gob.Register(&History{})
 }
@@ -0,0 +1,186 @@
+package store
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/namvu9/keylime/src/errors"
+)
+
+// staleID identifies an object that is deleted without
+// being loaded, such as the history of a document
+type staleID string
+
+func (id staleID) ID() string {
+	return string(id)
+}
+
+// checkReferrers returns an error with code EConflict if
+// another collection refers to the collection, or any
+// collection if `self` is set
+func (c *Collection) checkReferrers(self bool) error {
+	for _, ref := range c.Referrers {
+		if ref.Collection != c.Name || self {
+			return errors.Wrap("(*Collection).checkReferrers", errors.EConflict, fmt.Errorf("Collection %s is referred to by %s.%s", c.Name, ref.Collection, ref.Field))
+		}
+	}
+
+	return nil
+}
+
+// renameReferrers replaces the referrers recorded by the
+// collection on the collections it refers to with ones
+// from the collection `to`, or removes them if `to` is
+// empty
+func (c *Collection) renameReferrers(to string) error {
+	for _, ref := range c.Schema.References() {
+		if ref.Collection == c.Name {
+			continue
+		}
+
+		target, err := c.lookup(ref.Collection)
+		if errors.GetKind(err) == errors.ENotFound {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		var referrers []Referrer
+		for _, r := range target.Referrers {
+			if r.Collection == c.Name {
+				if to == "" {
+					continue
+				}
+				r.Collection = to
+			}
+			referrers = append(referrers, r)
+		}
+		target.Referrers = referrers
+
+		if err := target.commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// drop deletes every object in the collection's scope,
+// including the collection itself, once the operations in
+// progress are done. Later operations fail with code
+// ENotFound.
+func (c *Collection) drop() error {
+	var op errors.Op = "(*Collection).drop"
+
+	err := c.exclusive(true, func() error {
+		log.Printf("Dropping collection %s\n", c.ID())
+
+		if err := c.checkReferrers(false); err != nil {
+			return err
+		}
+
+		if err := c.Index.Drop(); err != nil {
+			return errors.Wrap(op, errors.EInternal, err)
+		}
+
+		for _, idx := range c.Unique {
+			if err := idx.Drop(); err != nil {
+				return errors.Wrap(op, errors.EInternal, err)
+			}
+		}
+
+		if err := c.Blocks.drop(); err != nil {
+			return errors.Wrap(op, errors.EInternal, err)
+		}
+
+		if err := c.repo.Delete(c); err != nil {
+			return errors.Wrap(op, errors.EInternal, err)
+		}
+
+		// Histories, and any other object left in the scope
+		ids, err := c.repo.List()
+		if err != nil {
+			return errors.Wrap(op, errors.EInternal, err)
+		}
+
+		for _, id := range ids {
+			if err := c.repo.Delete(staleID(id)); err != nil {
+				return errors.Wrap(op, errors.EInternal, err)
+			}
+		}
+
+		if err := c.renameReferrers(""); err != nil {
+			return errors.Wrap(op, errors.GetKind(err), err)
+		}
+
+		if err := c.repo.Flush(); err != nil {
+			return errors.Wrap(op, errors.EInternal, err)
+		}
+
+		if err := c.repo.DropScope(); err != nil {
+			return errors.Wrap(op, errors.EInternal, err)
+		}
+
+		log.Printf("Done dropping collection %s\n", c.ID())
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	return nil
+}
+
+// rename moves the collection to the scope `to` once the
+// operations in progress are done. The collection may not be
+// referred to, as the references would have to be renamed
+// along with it.
+func (c *Collection) rename(to string) error {
+	var op errors.Op = "(*Collection).rename"
+
+	err := c.exclusive(false, func() error {
+		log.Printf("Renaming collection %s to %s\n", c.ID(), to)
+		from := c.Name
+
+		if err := c.checkReferrers(true); err != nil {
+			return err
+		}
+
+		repo, err := c.repo.Rename(to)
+		if err != nil {
+			return errors.Wrap(op, errors.EInternal, err)
+		}
+
+		if err := c.renameReferrers(to); err != nil {
+			return errors.Wrap(op, errors.GetKind(err), err)
+		}
+
+		c.Name = to
+		c.repo = repo
+		if err := c.load(); err != nil {
+			return errors.Wrap(op, errors.EInternal, err)
+		}
+
+		// The header is saved under the new name before the old
+		// one is removed
+		if err := c.repo.Save(c); err != nil {
+			return errors.Wrap(op, errors.EInternal, err)
+		}
+
+		if err := c.repo.Delete(staleID(from)); err != nil {
+			return errors.Wrap(op, errors.EInternal, err)
+		}
+
+		if err := c.repo.Flush(); err != nil {
+			return errors.Wrap(op, errors.EInternal, err)
+		}
+
+		log.Printf("Done renaming collection %s to %s\n", from, to)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	return nil
+}
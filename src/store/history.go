@@ -0,0 +1,228 @@
+package store
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/namvu9/keylime/src/errors"
+	"github.com/namvu9/keylime/src/repository"
+	"github.com/namvu9/keylime/src/types"
+)
+
+// A History holds the previous revisions of a document,
+// ordered from oldest to newest.
+type History struct {
+	Identifier string
+	Revisions  []types.Document
+}
+
+func (h *History) ID() string {
+	return h.Identifier
+}
+
+// historyID returns the repository ID of the history of
+// the document with key `k`. The key is hex-encoded since
+// it may contain characters that are not valid in a file
+// name.
+func historyID(k string) string {
+	return fmt.Sprintf("history-%s", hex.EncodeToString([]byte(k)))
+}
+
+// GetRevision returns the revision `rev` of the document
+// with key `k`. An error with code ENotFound is returned if
+// the revision is neither the current one nor kept in the
+// document's history.
+func (c *Collection) GetRevision(ctx context.Context, k string, rev int) (*types.Document, error) {
+	if err := c.rlock(); err != nil {
+		return nil, err
+	}
+	defer c.runlock()
+
+	var op errors.Op = "(*Collection).GetRevision"
+
+	revisions, err := c.revisions(ctx, k)
+	if err != nil {
+		return nil, errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	for _, doc := range revisions {
+		if doc.Revision == rev {
+			fullDoc := c.Schema.WithDefaults(doc)
+			return &fullDoc, nil
+		}
+	}
+
+	return nil, errors.Wrap(op, errors.ENotFound, fmt.Errorf("Revision %d of %s not found", rev, k))
+}
+
+// History returns the revisions of the document with key
+// `k` kept by the collection, ordered from oldest to
+// newest. The last element is the current revision, unless
+// the document has been deleted.
+func (c *Collection) History(ctx context.Context, k string) ([]types.Document, error) {
+	if err := c.rlock(); err != nil {
+		return nil, err
+	}
+	defer c.runlock()
+
+	return c.revisions(ctx, k)
+}
+
+func (c *Collection) revisions(ctx context.Context, k string) ([]types.Document, error) {
+	var op errors.Op = "(*Collection).History"
+
+	var out []types.Document
+
+	h, err := c.history(k)
+	if err != nil {
+		return nil, errors.Wrap(op, errors.EInternal, err)
+	}
+
+	if h != nil {
+		out = append(out, h.Revisions...)
+	}
+
+	doc, err := c.get(ctx, k)
+	if errors.GetKind(err) == errors.ENotFound {
+		if len(out) == 0 {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, errors.Wrap(op, errors.EInternal, err)
+	} else {
+		out = append(out, *doc)
+	}
+
+	return out, nil
+}
+
+// history loads the history of the document with key `k`.
+// It returns nil if the collection does not keep history
+// or if no history exists for the document.
+func (c *Collection) history(k string) (*History, error) {
+	if c.Config.HistorySize == 0 {
+		return nil, nil
+	}
+
+	item, err := c.historyRepo().Get(historyID(k))
+	if err != nil {
+		return nil, err
+	}
+
+	if item == nil {
+		return nil, nil
+	}
+
+	h, ok := item.(*History)
+	if !ok {
+		return nil, fmt.Errorf("Item with ID %s did not have type History", historyID(k))
+	}
+
+	return h, nil
+}
+
+// archive appends `doc` to the history of its key, keeping
+// at most Config.HistorySize revisions. It is a no-op if
+// the collection does not keep history.
+func (c *Collection) archive(doc types.Document) error {
+	if c.Config.HistorySize == 0 {
+		return nil
+	}
+
+	h, err := c.history(doc.Key)
+	if err != nil {
+		return err
+	}
+
+	if h == nil {
+		h = &History{Identifier: historyID(doc.Key)}
+	}
+
+	h.Revisions = append(h.Revisions, doc)
+	if n := len(h.Revisions) - c.Config.HistorySize; n > 0 {
+		h.Revisions = h.Revisions[n:]
+	}
+
+	return c.historyRepo().Save(h)
+}
+
+// nextRevision returns the revision number of a new
+// document with key `k`. Numbering continues from the
+// tombstone of the key, if it was deleted, such that
+// revisions remain monotonic if a deleted key is set again.
+// The tombstone is removed.
+func (c *Collection) nextRevision(ctx context.Context, k string) (int, error) {
+	// Collections created before tombstones were kept only
+	// continue from the history
+	if c.Tombstones == nil {
+		h, err := c.history(k)
+		if err != nil {
+			return 0, err
+		}
+
+		if h == nil || len(h.Revisions) == 0 {
+			return 1, nil
+		}
+
+		return h.Revisions[len(h.Revisions)-1].Revision + 1, nil
+	}
+
+	rec, err := c.Tombstones.Get(ctx, k)
+	if errors.GetKind(err) == errors.ENotFound {
+		return 1, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	rev, err := strconv.Atoi(rec.Hash)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid tombstone for %s: %s", k, rec.Hash)
+	}
+
+	if err := c.Tombstones.Delete(ctx, k); err != nil {
+		return 0, err
+	}
+
+	return rev + 1, nil
+}
+
+// addTombstone records the revision of the deleted document
+// `doc`, whose key is no longer in the index
+func (c *Collection) addTombstone(ctx context.Context, doc types.Document) error {
+	if c.Tombstones == nil {
+		return nil
+	}
+
+	return c.Tombstones.Insert(ctx, doc.Key, "", versionOf(doc.Revision))
+}
+
+func (c *Collection) historyRepo() repository.Repository {
+	return repository.WithFactory(c.repo, repository.NoOpFactory{})
+}
+
+// historySize returns the size on disk of the histories of
+// the collection's documents
+func (c *Collection) historySize() (int64, error) {
+	ids, err := c.repo.List()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, id := range ids {
+		if !strings.HasPrefix(id, "history-") {
+			continue
+		}
+
+		size, err := c.repo.Size(id)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+
+	return total, nil
+}
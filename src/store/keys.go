@@ -0,0 +1,169 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/namvu9/keylime/src/errors"
+	"github.com/namvu9/keylime/src/types"
+)
+
+// Insert writes a document with the given fields under a
+// key generated by the collection's key strategy, and
+// returns the key
+func (c *Collection) Insert(ctx context.Context, fields Fields, opts ...types.DocumentOption) (string, error) {
+	if err := c.ensure(ctx); err != nil {
+		return "", err
+	}
+
+	unlock, err := c.lock()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	var op errors.Op = "(*Collection).Insert"
+
+	for {
+		k, err := c.nextKey()
+		if err != nil {
+			return "", errors.Wrap(op, errors.EInternal, err)
+		}
+
+		// Keys that were set explicitly are skipped
+		if _, err := c.Index.Get(ctx, k); err == nil {
+			continue
+		} else if errors.GetKind(err) != errors.ENotFound {
+			return "", errors.Wrap(op, errors.EInternal, err)
+		}
+
+		log.Printf("Inserting %s = %v in %s\n", k, fields, c.ID())
+
+		if err := c.set(ctx, k, fields, opts...); err != nil {
+			return "", errors.Wrap(op, errors.GetKind(err), err)
+		}
+
+		return k, nil
+	}
+}
+
+// nextKey generates a key according to the collection's key
+// strategy. The sequence is saved along with the
+// collection.
+func (c *Collection) nextKey() (string, error) {
+	switch c.Config.Keys {
+	case "", types.UUIDKeys:
+		return uuid.New().String(), nil
+	case types.ULIDKeys:
+		return newULID(time.Now())
+	case types.UUIDv7Keys:
+		return newUUIDv7(time.Now())
+	case types.SequenceKeys:
+		c.Sequence++
+		if err := c.commit(); err != nil {
+			c.Sequence--
+			return "", err
+		}
+
+		return sequenceKey(c.Sequence), nil
+	default:
+		return "", fmt.Errorf("Unknown key strategy: %s", c.Config.Keys)
+	}
+}
+
+// sequenceKey returns the key for sequence number `n`. Keys
+// are padded to a fixed width, such that they sort in the
+// order of the sequence.
+func sequenceKey(n int64) string {
+	return fmt.Sprintf("%020d", n)
+}
+
+// crockford is the alphabet of ULIDs, which leaves out I, L,
+// O and U
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// lastULID is the ULID returned last, which newULID
+// increments for ULIDs generated within the same
+// millisecond
+var lastULID struct {
+	sync.Mutex
+	b [16]byte
+}
+
+// newULID returns a ULID for time `t`: 48 bits of Unix time
+// in milliseconds followed by 80 random bits, encoded as 26
+// characters that sort in the order of the time. ULIDs
+// generated within the same millisecond as the last one, or
+// before it, increment its random bits instead, such that
+// they sort in the order they were generated.
+func newULID(t time.Time) (string, error) {
+	var b [16]byte
+
+	ms := uint64(t.UnixNano() / int64(time.Millisecond))
+	binary.BigEndian.PutUint64(b[:8], ms<<16)
+
+	lastULID.Lock()
+	if bytes.Compare(b[:6], lastULID.b[:6]) <= 0 {
+		b = lastULID.b
+		if !increment(b[6:]) {
+			lastULID.Unlock()
+			return "", fmt.Errorf("Too many ULIDs generated within a millisecond")
+		}
+	} else if _, err := rand.Read(b[6:]); err != nil {
+		lastULID.Unlock()
+		return "", err
+	}
+	lastULID.b = b
+	lastULID.Unlock()
+
+	// 128 bits are encoded 5 at a time, from the top, with 2
+	// bits of padding in front
+	out := make([]byte, 26)
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(out), nil
+}
+
+// increment adds 1 to the big-endian number `b`. It returns
+// false if the number overflows.
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newUUIDv7 returns a UUIDv7 for time `t`: 48 bits of Unix
+// time in milliseconds followed by the version, variant and
+// random bits
+func newUUIDv7(t time.Time) (string, error) {
+	var u uuid.UUID
+
+	ms := uint64(t.UnixNano() / int64(time.Millisecond))
+	binary.BigEndian.PutUint64(u[:8], ms<<16)
+	if _, err := rand.Read(u[6:]); err != nil {
+		return "", err
+	}
+
+	u[6] = u[6]&0x0f | 0x70 // Version 7
+	u[8] = u[8]&0x3f | 0x80 // RFC 4122 variant
+
+	return u.String(), nil
+}
@@ -0,0 +1,304 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/namvu9/keylime/src/errors"
+	"github.com/namvu9/keylime/src/types"
+)
+
+// guard serializes the operations on a collection. Reads
+// hold it shared, while writes, creation, drops and renames
+// hold it exclusively. An operation that also reads or
+// writes other collections, such as a write that checks
+// the documents it refers to, holds their guards as well.
+// Guards are always taken in lock order, the order in
+// which the collections were loaded, such that operations
+// on collections that refer to each other do not deadlock.
+//
+// Operations only take guards on entry; methods called
+// while a guard is held must not take it again.
+type guard struct {
+	sync.RWMutex
+
+	order   uint64
+	closed  bool
+	created bool
+}
+
+// lockOrder is the last lock order assigned to a collection
+var lockOrder uint64
+
+// initGuard assigns the collection its lock order, unless
+// it already has one
+func (c *Collection) initGuard() {
+	if c.guard.order == 0 {
+		c.guard.order = atomic.AddUint64(&lockOrder, 1)
+	}
+}
+
+// checkOpen returns an error with code ENotFound if the
+// collection has not been created, or has been dropped. The
+// guard must be held.
+func (c *Collection) checkOpen() error {
+	if c.guard.closed || !c.guard.created {
+		return errors.NewCollectionNotFoundError("(*Collection).checkOpen", c.ID())
+	}
+
+	return nil
+}
+
+// rlock takes the guard shared for a read. An error with
+// code ENotFound is returned if the collection has not been
+// created, or has been dropped.
+func (c *Collection) rlock() error {
+	c.guard.RLock()
+
+	if err := c.checkOpen(); err != nil {
+		c.guard.RUnlock()
+		return err
+	}
+
+	return nil
+}
+
+// runlock releases the guard taken by rlock
+func (c *Collection) runlock() {
+	c.guard.RUnlock()
+}
+
+// lock takes the guard exclusively for a write, along with
+// the guards of the collections that the write reads,
+// shared: the collections that the schema refers to, and
+// the collections whose Restrict fields refer to this one.
+// The returned function releases them.
+func (c *Collection) lock() (func(), error) {
+	return c.acquire(c.checkOpen, func(locks) locks {
+		l := make(locks)
+		c.addWriteLocks(l)
+		return l
+	})
+}
+
+// lockDelete takes the guards that lock takes for a delete,
+// along with the guards of the collections that the Cascade
+// and SetNull policies write to, exclusively, and of the
+// collections that those writes read, shared. The policies
+// are thereby applied along with the delete.
+func (c *Collection) lockDelete() (func(), error) {
+	return c.acquire(c.checkOpen, func(held locks) locks {
+		l := make(locks)
+		c.addDeleteLocks(l, held, make(map[*Collection]bool))
+		return l
+	})
+}
+
+// addWriteLocks adds the collections that a write to the
+// collection reads to `l`
+func (c *Collection) addWriteLocks(l locks) {
+	for _, ref := range c.Schema.References() {
+		c.addLock(l, ref.Collection, false)
+	}
+
+	for _, r := range c.Referrers {
+		if r.OnDelete == types.Restrict {
+			c.addLock(l, r.Collection, false)
+		}
+	}
+}
+
+// addDeleteLocks adds the collections that a delete from
+// the collection writes to or reads to `l`, following the
+// Cascade and SetNull policies of the collections that it
+// writes to. Only the collections in `held` are followed, as
+// the others may change until their guards are held.
+func (c *Collection) addDeleteLocks(l, held locks, seen map[*Collection]bool) {
+	if seen[c] {
+		return
+	}
+	seen[c] = true
+
+	c.addWriteLocks(l)
+
+	for _, r := range c.Referrers {
+		if r.OnDelete != types.Cascade && r.OnDelete != types.SetNull {
+			continue
+		}
+
+		rc := c.addLock(l, r.Collection, true)
+		if _, ok := held[rc]; ok {
+			rc.addDeleteLocks(l, held, seen)
+		}
+	}
+}
+
+// lockCreate takes the guard exclusively for the creation of
+// the collection with the schema `s`, along with the guards
+// of the collections that it refers to, which record it as
+// a referrer. An error with code EConflict is returned if
+// the collection already exists.
+func (c *Collection) lockCreate(s *types.Schema) (func(), error) {
+	check := func() error {
+		if c.guard.closed {
+			return errors.NewCollectionNotFoundError("(*Collection).lockCreate", c.ID())
+		}
+
+		if c.guard.created {
+			return errors.NewCollectionExistsError("(*Collection).lockCreate", c.ID())
+		}
+
+		return nil
+	}
+
+	return c.acquire(check, func(locks) locks {
+		l := make(locks)
+		if s != nil {
+			for _, ref := range s.References() {
+				c.addLock(l, ref.Collection, true)
+			}
+		}
+
+		return l
+	})
+}
+
+// exclusive runs `fn` while holding the guard exclusively,
+// along with the guards of the collections that the schema
+// refers to, whose referrers are updated by drops and
+// renames. If `drop` is set and `fn` succeeds, later
+// operations fail with code ENotFound.
+func (c *Collection) exclusive(drop bool, fn func() error) error {
+	unlock, err := c.acquire(c.checkOpen, func(locks) locks {
+		l := make(locks)
+		for _, ref := range c.Schema.References() {
+			c.addLock(l, ref.Collection, true)
+		}
+
+		return l
+	})
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	if drop {
+		c.guard.closed = true
+	}
+
+	return nil
+}
+
+// ensure creates the collection, without a schema, if it
+// does not exist and the store creates collections on their
+// first write. It must be called before the guard is taken.
+func (c *Collection) ensure(ctx context.Context) error {
+	if !c.autoCreate {
+		return nil
+	}
+
+	err := c.Create(ctx, nil)
+	if err != nil && !errors.Is(err, errors.ErrCollectionExists) {
+		return err
+	}
+
+	return nil
+}
+
+// locks maps collections to whether their guards are to be
+// taken exclusively
+type locks map[*Collection]bool
+
+// addLock adds the collection with the given name to `l`,
+// unless it is `c` itself, and returns it. Collections that
+// do not exist are left out; the operation reports them when
+// it looks them up.
+func (c *Collection) addLock(l locks, name string, exclusive bool) *Collection {
+	if name == c.Name {
+		return c
+	}
+
+	target, err := c.lookup(name)
+	if err != nil {
+		return nil
+	}
+
+	l[target] = l[target] || exclusive
+	return target
+}
+
+// acquire takes the guard of `c` exclusively, along with the
+// guards returned by `others`, once `check` passes. Since
+// `others` reads the collections, it is evaluated while the
+// guards taken so far, `held`, are held. If it returns
+// guards that are not held, such as when a referrer was
+// added in between, every guard is released and taken again
+// in lock order, along with the new ones.
+func (c *Collection) acquire(check func() error, others func(held locks) locks) (func(), error) {
+	want := locks{c: true}
+	for {
+		release := want.lock()
+		if err := check(); err != nil {
+			release()
+			return nil, err
+		}
+
+		got := others(want)
+		if want.covers(got) {
+			return release, nil
+		}
+		release()
+
+		for o, exclusive := range got {
+			want[o] = want[o] || exclusive
+		}
+	}
+}
+
+// lock takes the guards in lock order, and returns a
+// function that releases them
+func (l locks) lock() func() {
+	cs := make([]*Collection, 0, len(l))
+	for c := range l {
+		cs = append(cs, c)
+	}
+	sort.Slice(cs, func(i, j int) bool {
+		return cs[i].guard.order < cs[j].guard.order
+	})
+
+	for _, c := range cs {
+		if l[c] {
+			c.guard.Lock()
+		} else {
+			c.guard.RLock()
+		}
+	}
+
+	return func() {
+		for i := len(cs) - 1; i >= 0; i-- {
+			if l[cs[i]] {
+				cs[i].guard.Unlock()
+			} else {
+				cs[i].guard.RUnlock()
+			}
+		}
+	}
+}
+
+// covers reports whether the guards in `other` are held by
+// `l`, in the same or a stronger mode
+func (l locks) covers(other locks) bool {
+	for c, exclusive := range other {
+		if e, ok := l[c]; !ok || exclusive && !e {
+			return false
+		}
+	}
+
+	return true
+}
@@ -1,11 +1,25 @@
 package store
 
+import "time"
+
 // Config represnts the configuration used to initialize the
 // store
 type Config struct {
 	BaseDir string
 	Port    string
 	Host    string
+
+	// ReapInterval is how often expired documents are deleted
+	ReapInterval time.Duration
+
+	// AutoCreate makes the first write to a collection that
+	// does not exist create it without a schema. Otherwise,
+	// writing to it fails with code ENotFound.
+	AutoCreate bool
+
+	// ImportDir is the directory that LOAD reads files from.
+	// LOAD is disabled if it is empty.
+	ImportDir string
 }
 
 type Option func(*Store)
@@ -0,0 +1,275 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/namvu9/keylime/src/errors"
+	"github.com/namvu9/keylime/src/types"
+)
+
+// A Referrer is a field of a collection that refers to the
+// documents of another collection. It is recorded on the
+// referenced collection, such that its on-delete policy can
+// be applied when one of its documents is deleted.
+type Referrer struct {
+	Collection string
+	Field      string
+	OnDelete   types.RefPolicy
+}
+
+// lookup returns the collection with the given name, which
+// must exist
+func (c *Collection) lookup(name string) (*Collection, error) {
+	var op errors.Op = "(*Collection).lookup"
+
+	if name == c.Name {
+		return c, nil
+	}
+
+	if c.resolve == nil {
+		return nil, errors.Wrap(op, errors.EInternal, fmt.Errorf("Cannot look up collection %s from %s", name, c.Name))
+	}
+
+	return c.resolve(name)
+}
+
+// addReferrers records the reference fields of the schema
+// on the collections they refer to. Every referenced
+// collection must exist.
+func (c *Collection) addReferrers() error {
+	var op errors.Op = "(*Collection).addReferrers"
+
+	refs := c.Schema.References()
+
+	var names []string
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ref := refs[name]
+
+		target, err := c.lookup(ref.Collection)
+		if err != nil {
+			return errors.Wrap(op, errors.GetKind(err), fmt.Errorf("Field %s: %w", name, err))
+		}
+
+		target.Referrers = append(target.Referrers, Referrer{
+			Collection: c.Name,
+			Field:      name,
+			OnDelete:   ref.OnDelete,
+		})
+
+		if target == c {
+			continue
+		}
+
+		if err := target.commit(); err != nil {
+			return errors.Wrap(op, errors.EInternal, err)
+		}
+	}
+
+	return nil
+}
+
+// validate checks `doc` against the schema of the
+// collection and checks that the documents it refers to
+// exist. Only top-level fields may be references.
+func (c *Collection) validate(ctx context.Context, doc types.Document) error {
+	if err := c.Schema.Validate(doc); err != nil {
+		return err
+	}
+
+	ve := make(types.ValidationError)
+	for name, ref := range c.Schema.References() {
+		field, ok := doc.Fields[name]
+		if !ok || field.Value == nil {
+			continue
+		}
+
+		key, _ := field.Value.(string)
+
+		target, err := c.lookup(ref.Collection)
+		if err != nil {
+			return err
+		}
+
+		// The guard of the target is held by the write
+		_, err = target.get(ctx, key)
+		if errors.GetKind(err) == errors.ENotFound {
+			ve[name] = append(ve[name], fmt.Errorf("Key %s does not exist in %s", key, ref.Collection))
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if len(ve) > 0 {
+		return ve
+	}
+
+	return nil
+}
+
+// referringKeys returns the keys of the documents in the
+// collection whose field `field` is `k`
+func (c *Collection) referringKeys(ctx context.Context, field, k string) ([]string, error) {
+	cur, err := c.scan(ctx, true, types.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for cur.Next() {
+		doc := cur.Value()
+		if f, ok := doc.Fields[field]; ok && f.Value == k {
+			keys = append(keys, doc.Key)
+		}
+	}
+
+	return keys, cur.Err()
+}
+
+// checkRestrict returns an error with code EConflict if a
+// referrer with the Restrict policy refers to the document
+// with key `k`
+func (c *Collection) checkRestrict(ctx context.Context, k string) error {
+	var op errors.Op = "(*Collection).checkRestrict"
+
+	for _, r := range c.Referrers {
+		if r.OnDelete != types.Restrict {
+			continue
+		}
+
+		rc, err := c.lookup(r.Collection)
+		if errors.GetKind(err) == errors.ENotFound {
+			continue
+		} else if err != nil {
+			return errors.Wrap(op, errors.EInternal, err)
+		}
+
+		keys, err := rc.referringKeys(ctx, r.Field, k)
+		if err != nil {
+			return errors.Wrap(op, errors.EInternal, err)
+		}
+
+		if len(keys) > 0 {
+			return errors.Wrap(op, errors.EConflict, fmt.Errorf("%s is referred to by %s in %s", k, keys[0], r.Collection))
+		}
+	}
+
+	return nil
+}
+
+// applyReferrers applies the Cascade and SetNull policies
+// to the documents that referred to the deleted document
+// with key `k`. The guards taken by lockDelete must be held.
+// There are no transactions: if a policy fails, such as when
+// unsetting a required field, the error is returned and the
+// documents written up to then stay written.
+func (c *Collection) applyReferrers(ctx context.Context, k string) error {
+	var op errors.Op = "(*Collection).applyReferrers"
+
+	for _, r := range c.Referrers {
+		if r.OnDelete != types.Cascade && r.OnDelete != types.SetNull {
+			continue
+		}
+
+		rc, err := c.lookup(r.Collection)
+		if errors.GetKind(err) == errors.ENotFound {
+			continue
+		} else if err != nil {
+			return errors.Wrap(op, errors.EInternal, err)
+		}
+
+		// The collection may have been dropped
+		if rc.checkOpen() != nil {
+			continue
+		}
+
+		keys, err := rc.referringKeys(ctx, r.Field, k)
+		if err != nil {
+			return errors.Wrap(op, errors.EInternal, err)
+		}
+
+		unset := []types.UpdateOp{{Kind: types.UnsetOp, Path: []string{r.Field}}}
+		for _, key := range keys {
+			if r.OnDelete == types.Cascade {
+				err = rc.delete(ctx, key, "")
+			} else {
+				err = rc.update(ctx, key, "", func(doc types.Document) (types.Document, error) {
+					return doc.Apply(unset...)
+				})
+			}
+
+			if errors.GetKind(err) == errors.ENotFound {
+				continue
+			} else if err != nil {
+				return errors.Wrap(op, errors.GetKind(err), fmt.Errorf("Applying %s to %s in %s: %w", r.OnDelete, key, r.Collection, err))
+			}
+		}
+
+		if len(keys) > 0 {
+			log.Printf("Applied %s to %d documents in %s\n", r.OnDelete, len(keys), r.Collection)
+		}
+	}
+
+	return nil
+}
+
+// Expand returns a copy of `doc` in which each of the given
+// reference fields holds the document it refers to, or nil
+// if that document does not exist
+func (c *Collection) Expand(ctx context.Context, doc types.Document, fields ...string) (types.Document, error) {
+	if err := c.rlock(); err != nil {
+		return types.Document{}, err
+	}
+	refs := c.Schema.References()
+	c.runlock()
+
+	var op errors.Op = "(*Collection).Expand"
+
+	out := doc
+	out.Fields = make(map[string]types.Field, len(doc.Fields))
+	for name, f := range doc.Fields {
+		out.Fields[name] = f
+	}
+
+	for _, name := range fields {
+		ref, ok := refs[name]
+		if !ok {
+			return doc, errors.Wrap(op, errors.EBadRequest, fmt.Errorf("Field %s is not a reference", name))
+		}
+
+		f, ok := out.Fields[name]
+		if !ok || f.Value == nil {
+			continue
+		}
+
+		target, err := c.lookup(ref.Collection)
+		if err != nil {
+			return doc, errors.Wrap(op, errors.GetKind(err), err)
+		}
+
+		key, _ := f.Value.(string)
+		referred, err := target.Get(ctx, key)
+		if errors.GetKind(err) == errors.ENotFound {
+			out.Fields[name] = types.Field{Type: types.Object}
+			continue
+		} else if err != nil {
+			return doc, errors.Wrap(op, errors.EInternal, err)
+		}
+
+		obj := make(map[string]interface{}, len(referred.Fields))
+		for k, v := range referred.Fields {
+			obj[k] = v.Value
+		}
+
+		out.Fields[name] = types.Field{Type: types.Object, Value: obj}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,321 @@
+package store
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/namvu9/keylime/src/errors"
+	"github.com/namvu9/keylime/src/types"
+)
+
+// sortedPage returns a page of the documents in the
+// collection ordered by opts.OrderBy, or in the reverse
+// order if `asc` is false. Sorted listings are paginated
+// with opts.Skip; cursors are not supported.
+func (c *Collection) sortedPage(ctx context.Context, n int, asc bool, opts types.ListOptions) (*types.Page, error) {
+	var op errors.Op = "(*Collection).sortedPage"
+
+	if opts.After != "" {
+		return nil, errors.Wrap(op, errors.EBadRequest, fmt.Errorf("Cursors are not supported by ORDER BY, use SKIP instead"))
+	}
+
+	order := opts.OrderBy
+	if !asc {
+		order = order.Reverse()
+	}
+
+	cur, err := c.scan(ctx, true, types.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	sorted, err := sortDocuments(&schemaIterator{cur, c.Schema}, order)
+	if err != nil {
+		return nil, errors.Wrap(op, errors.EInternal, err)
+	}
+	defer sorted.Close()
+
+	for i := 0; i < opts.Skip && sorted.Next(); i++ {
+	}
+
+	page := &types.Page{Documents: []types.Document{}}
+	for len(page.Documents) < n && sorted.Next() {
+		page.Documents = append(page.Documents, sorted.Value())
+	}
+
+	if len(page.Documents) == n {
+		page.HasMore = sorted.Next()
+	}
+
+	if err := sorted.Err(); err != nil {
+		return nil, errors.Wrap(op, errors.EInternal, err)
+	}
+
+	return page, nil
+}
+
+// A schemaIterator applies the defaults of a schema to the
+// documents yielded by another iterator
+type schemaIterator struct {
+	types.DocumentIterator
+	schema types.Schema
+}
+
+func (s *schemaIterator) Value() types.Document {
+	return s.schema.WithDefaults(s.DocumentIterator.Value())
+}
+
+// sortRunSize is the number of documents sorted in memory
+// before they are spilled to disk
+var sortRunSize = 10000
+
+// A sortedIterator yields documents in sorted order. It
+// must be closed to remove the runs spilled to disk.
+type sortedIterator interface {
+	types.DocumentIterator
+	io.Closer
+}
+
+// sortDocuments reads every document from `it` and returns
+// an iterator over them in the order given by `order`. Up
+// to sortRunSize documents are sorted in memory. Larger
+// inputs are sorted in runs that are written to temporary
+// files and merged as the result is iterated.
+func sortDocuments(it types.DocumentIterator, order types.OrderBy) (sortedIterator, error) {
+	var (
+		runs []*run
+		buf  []types.Document
+	)
+
+	cleanup := func() {
+		for _, r := range runs {
+			r.Close()
+		}
+	}
+
+	for it.Next() {
+		buf = append(buf, it.Value())
+
+		if len(buf) == sortRunSize {
+			r, err := spill(buf, order)
+			if err != nil {
+				cleanup()
+				return nil, err
+			}
+
+			runs = append(runs, r)
+			buf = buf[:0]
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	sortRun(buf, order)
+
+	if len(runs) == 0 {
+		return &sliceIterator{docs: buf, i: -1}, nil
+	}
+
+	log.Printf("Sort: merging %d runs\n", len(runs)+1)
+
+	m := &mergeIterator{order: order, runs: runs}
+	if len(buf) > 0 {
+		m.runs = append(m.runs, &run{mem: buf, i: -1})
+	}
+
+	for _, r := range m.runs {
+		if r.next() {
+			m.heap = append(m.heap, r)
+		} else if r.err != nil {
+			m.Close()
+			return nil, r.err
+		}
+	}
+	heap.Init(m)
+
+	return m, nil
+}
+
+func sortRun(docs []types.Document, order types.OrderBy) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		return order.Compare(docs[i], docs[j]) < 0
+	})
+}
+
+// spill sorts the documents and writes them to a temporary
+// file
+func spill(docs []types.Document, order types.OrderBy) (*run, error) {
+	sortRun(docs, order)
+
+	f, err := os.CreateTemp("", "keylime-sort-*")
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Sort: spilling %d documents to %s\n", len(docs), f.Name())
+
+	w := bufio.NewWriter(f)
+	enc := gob.NewEncoder(w)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return nil, err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	return &run{f: f, dec: gob.NewDecoder(bufio.NewReader(f))}, nil
+}
+
+// A run is a sorted sequence of documents, either in a
+// file or in memory
+type run struct {
+	f   *os.File
+	dec *gob.Decoder
+
+	mem []types.Document
+	i   int
+
+	doc types.Document
+	err error
+}
+
+func (r *run) next() bool {
+	if r.f == nil {
+		r.i++
+		if r.i >= len(r.mem) {
+			return false
+		}
+
+		r.doc = r.mem[r.i]
+		return true
+	}
+
+	var doc types.Document
+	if err := r.dec.Decode(&doc); err != nil {
+		if err != io.EOF {
+			r.err = err
+		}
+		return false
+	}
+
+	r.doc = doc
+	return true
+}
+
+func (r *run) Close() error {
+	if r.f == nil {
+		return nil
+	}
+
+	r.f.Close()
+	return os.Remove(r.f.Name())
+}
+
+// A mergeIterator merges sorted runs. It implements
+// heap.Interface over the runs that have documents left,
+// ordered by their current document.
+type mergeIterator struct {
+	order types.OrderBy
+	runs  []*run
+	heap  []*run
+
+	doc types.Document
+	err error
+}
+
+func (m *mergeIterator) Next() bool {
+	if len(m.heap) == 0 || m.err != nil {
+		return false
+	}
+
+	r := m.heap[0]
+	m.doc = r.doc
+
+	if r.next() {
+		heap.Fix(m, 0)
+	} else if r.err != nil {
+		m.err = r.err
+	} else {
+		heap.Pop(m)
+	}
+
+	return true
+}
+
+func (m *mergeIterator) Value() types.Document {
+	return m.doc
+}
+
+func (m *mergeIterator) Err() error {
+	return m.err
+}
+
+// Close removes the runs spilled to disk
+func (m *mergeIterator) Close() error {
+	var err error
+	for _, r := range m.runs {
+		if cerr := r.Close(); cerr != nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+func (m *mergeIterator) Len() int { return len(m.heap) }
+func (m *mergeIterator) Less(i, j int) bool {
+	return m.order.Compare(m.heap[i].doc, m.heap[j].doc) < 0
+}
+func (m *mergeIterator) Swap(i, j int)      { m.heap[i], m.heap[j] = m.heap[j], m.heap[i] }
+func (m *mergeIterator) Push(x interface{}) { m.heap = append(m.heap, x.(*run)) }
+func (m *mergeIterator) Pop() interface{} {
+	r := m.heap[len(m.heap)-1]
+	m.heap = m.heap[:len(m.heap)-1]
+	return r
+}
+
+// A sliceIterator iterates over documents in memory
+type sliceIterator struct {
+	docs []types.Document
+	i    int
+}
+
+func (s *sliceIterator) Next() bool {
+	s.i++
+	return s.i < len(s.docs)
+}
+
+func (s *sliceIterator) Value() types.Document {
+	return s.docs[s.i]
+}
+
+func (s *sliceIterator) Err() error {
+	return nil
+}
+
+func (s *sliceIterator) Close() error {
+	return nil
+}
@@ -2,10 +2,19 @@ package store
 
 import (
 	"bytes"
+	"context"
 	"encoding/gob"
 	"fmt"
 	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/namvu9/keylime/src/errors"
 	"github.com/namvu9/keylime/src/repository"
 	"github.com/namvu9/keylime/src/types"
 )
@@ -15,6 +24,15 @@ type Store struct {
 	t       int
 
 	repo repository.Repository
+
+	mu          sync.Mutex
+	collections map[string]*Collection // Collections loaded by the store
+
+	// autoCreate makes the first write to a collection that
+	// does not exist create it
+	autoCreate bool
+
+	importDir string
 }
 
 type CollectionFactory struct {
@@ -43,27 +61,257 @@ func newCollectionFactory(r repository.Repository) CollectionFactory {
 	}
 }
 
+// Collection returns the collection with the given name.
+// If it does not exist, operations other than Create fail
+// with code ENotFound, unless the store creates collections
+// on their first write.
 func (s *Store) Collection(name string) (types.Collection, error) {
+	return s.get(name)
+}
+
+// get returns the collection with the given name, loading it
+// if it exists. The store returns the same collection for a
+// name until it is dropped or renamed, whether it has been
+// created or not, such that operations on it, including
+// concurrent creations, are serialized by its guard.
+func (s *Store) get(name string) (*Collection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.collections[name]; ok {
+		return c, nil
+	}
+
+	repo := repository.WithScope(s.repo, name)
+
+	var c *Collection
+	if ok, err := repo.Exists(name); err != nil {
+		return nil, err
+	} else if ok {
+		item, err := repo.Get(name)
+		if err != nil {
+			return nil, err
+		}
+
+		c, ok = item.(*Collection)
+		if !ok {
+			return nil, fmt.Errorf("Could not load collection %s", name)
+		}
+	} else {
+		c = newCollection(name, s.repo)
+		c.autoCreate = s.autoCreate
+	}
+
+	c.resolve = s.collection
+	s.collections[name] = c
+
+	return c, nil
+}
+
+// collection returns the existing collection with the given
+// name. An error with code ENotFound is returned if it does
+// not exist.
+func (s *Store) collection(name string) (*Collection, error) {
+	var op errors.Op = "(*Store).collection"
+
 	repo := repository.WithScope(s.repo, name)
 
 	if ok, err := repo.Exists(name); !ok && err == nil {
-		c := newCollection(name, s.repo)
-		return c, nil
+		return nil, errors.NewCollectionNotFoundError(op, name)
 	} else if err != nil {
-		return nil, err
+		return nil, errors.Wrap(op, errors.EInternal, err)
 	}
 
-	item, err := repo.Get(name)
+	c, err := s.get(name)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(op, errors.EInternal, err)
 	}
 
-	c, ok := item.(*Collection)
-	if !ok {
-		return nil, fmt.Errorf("Could not load collection %s", name)
+	return c, nil
+}
+
+// Collections returns the names of the collections in the
+// store in alphabetical order
+func (s *Store) Collections(ctx context.Context) ([]string, error) {
+	var op errors.Op = "(*Store).Collections"
+
+	scopes, err := s.repo.Scopes()
+	if err != nil {
+		return nil, errors.Wrap(op, errors.EInternal, err)
 	}
 
-	return c, nil
+	var names []string
+	for _, name := range scopes {
+		repo := repository.WithScope(s.repo, name)
+		if ok, err := repo.Exists(name); err != nil {
+			return nil, errors.Wrap(op, errors.EInternal, err)
+		} else if ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// OpenImport opens the file at `path`, relative to the
+// import directory, for reading. Paths that resolve to a
+// file outside the import directory, including through
+// symbolic links, are rejected with code EBadRequest, as is
+// every path if no import directory is configured.
+func (s *Store) OpenImport(path string) (io.ReadCloser, error) {
+	var op errors.Op = "(*Store).OpenImport"
+
+	if s.importDir == "" {
+		return nil, errors.Wrap(op, errors.EBadRequest, fmt.Errorf("LOAD is disabled, as no import directory is configured"))
+	}
+
+	dir, err := filepath.Abs(s.importDir)
+	if err == nil {
+		dir, err = filepath.EvalSymlinks(dir)
+	}
+	if err != nil {
+		return nil, errors.Wrap(op, errors.EInternal, err)
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, errors.Wrap(op, errors.EBadRequest, fmt.Errorf("Could not open %s", path))
+	}
+
+	rel, err := filepath.Rel(dir, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, errors.Wrap(op, errors.EBadRequest, fmt.Errorf("%s is outside of the import directory", path))
+	}
+
+	f, err := os.Open(resolved)
+	if err != nil {
+		return nil, errors.Wrap(op, errors.EBadRequest, err)
+	}
+
+	return f, nil
+}
+
+// Drop deletes the collection with the given name and all of
+// its documents once the operations in progress on it are
+// done. An error with code ENotFound is returned if it does
+// not exist, and EConflict if another collection refers to
+// it.
+func (s *Store) Drop(ctx context.Context, name string) error {
+	var op errors.Op = "(*Store).Drop"
+
+	c, err := s.collection(name)
+	if err != nil {
+		return errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	if err := c.drop(); err != nil {
+		return errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	s.mu.Lock()
+	delete(s.collections, name)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Rename renames the collection `from` to `to` once the
+// operations in progress on it are done. An error with code
+// ENotFound is returned if `from` does not exist, and
+// EConflict if `to` exists or another collection refers to
+// `from`.
+func (s *Store) Rename(ctx context.Context, from, to string) error {
+	var op errors.Op = "(*Store).Rename"
+
+	if to == "" || strings.ContainsAny(to, `/\.`) {
+		return errors.Wrap(op, errors.EBadRequest, fmt.Errorf("Invalid collection name %q", to))
+	}
+
+	c, err := s.collection(from)
+	if err != nil {
+		return errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	// The collection `to` is held as if it were being created,
+	// such that it is not created while `from` is renamed
+	pending, err := s.get(to)
+	if err != nil {
+		return errors.Wrap(op, errors.EInternal, err)
+	}
+
+	unlock, err := pending.lockCreate(nil)
+	if errors.GetKind(err) == errors.EConflict {
+		return errors.Wrap(op, errors.EConflict, fmt.Errorf("Collection %s already exists", to))
+	} else if err != nil {
+		return errors.Wrap(op, errors.GetKind(err), err)
+	}
+	defer unlock()
+
+	if err := c.rename(to); err != nil {
+		return errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	// Operations on the pending collection fail from now on
+	pending.guard.closed = true
+
+	s.mu.Lock()
+	delete(s.collections, from)
+	s.collections[to] = c
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Reap deletes the expired documents in every collection in
+// the store, loading those that have not been loaded yet
+func (s *Store) Reap(ctx context.Context) error {
+	var op errors.Op = "(*Store).Reap"
+
+	names, err := s.Collections(ctx)
+	if err != nil {
+		return errors.Wrap(op, errors.GetKind(err), err)
+	}
+
+	for _, name := range names {
+		// The collection may have been dropped since
+		c, err := s.collection(name)
+		if errors.GetKind(err) == errors.ENotFound {
+			continue
+		} else if err != nil {
+			return errors.Wrap(op, errors.GetKind(err), err)
+		}
+
+		if _, err := c.Reap(ctx); errors.GetKind(err) == errors.ENotFound {
+			continue
+		} else if err != nil {
+			return errors.Wrap(op, errors.GetKind(err), err)
+		}
+	}
+
+	return nil
+}
+
+// StartReaper calls Reap every `interval` until the context
+// is cancelled
+func (s *Store) StartReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Reap(ctx); err != nil {
+				log.Printf("Reaper: %s\n", err)
+			}
+		}
+	}
 }
 
 type DefaultCodec struct{}
@@ -94,8 +342,11 @@ func (dc DefaultCodec) Decode(r io.Reader, dst interface{}) error {
 // options
 func New(cfg *Config, opts ...Option) *Store {
 	s := &Store{
-		baseDir: cfg.BaseDir,
-		repo:    repository.New(cfg.BaseDir, DefaultCodec{}, repository.NewFS(cfg.BaseDir)),
+		baseDir:     cfg.BaseDir,
+		autoCreate:  cfg.AutoCreate,
+		importDir:   cfg.ImportDir,
+		repo:        repository.New(cfg.BaseDir, DefaultCodec{}, repository.NewFS(cfg.BaseDir)),
+		collections: make(map[string]*Collection),
 	}
 
 	for _, opt := range opts {
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/namvu9/keylime/src/errors"
+	"github.com/namvu9/keylime/src/index"
+	"github.com/namvu9/keylime/src/types"
+)
+
+// uniqueValues holds the values of the unique fields of a
+// batch of documents that have not been written yet, by
+// field and then by value
+type uniqueValues map[string]map[string]string
+
+// createUniqueIndexes creates an index for each unique field
+// of the schema, which maps the values of the field to the
+// keys of the documents that hold them
+func (c *Collection) createUniqueIndexes() error {
+	c.Unique = make(map[string]*index.Index)
+
+	for _, name := range c.Schema.UniqueFields() {
+		idx := index.New(50, c.repo)
+		if err := idx.Create(); err != nil {
+			return err
+		}
+
+		c.Unique[name] = &idx
+	}
+
+	return nil
+}
+
+// checkUnique returns an error with code EConflict if a
+// unique field of `doc` has the same value as the field of
+// another document, either in the collection or in
+// `pending`. The values of `doc` are added to `pending`
+// unless it is nil.
+func (c *Collection) checkUnique(ctx context.Context, doc types.Document, pending uniqueValues) error {
+	var op errors.Op = "(*Collection).checkUnique"
+
+	ve := make(types.ValidationError)
+	for name, idx := range c.Unique {
+		field, ok := doc.Fields[name]
+		if !ok || field.Value == nil {
+			continue
+		}
+
+		value := types.UniqueKey(field.Value)
+
+		owner := pending[name][value]
+		if owner == "" {
+			rec, err := idx.Get(ctx, value)
+			if err == nil {
+				owner = rec.Value
+			} else if errors.GetKind(err) != errors.ENotFound {
+				return errors.Wrap(op, errors.EInternal, err)
+			}
+
+			// The value of an expired document that has not been
+			// reaped yet may be reused
+			if owner != "" && owner != doc.Key {
+				if _, err := c.get(ctx, owner); errors.GetKind(err) == errors.ENotFound {
+					owner = ""
+				}
+			}
+		}
+
+		if owner != "" && owner != doc.Key {
+			ve[name] = append(ve[name], fmt.Errorf("Value %v already exists in %s", field.Value, owner))
+			continue
+		}
+
+		if pending != nil {
+			if pending[name] == nil {
+				pending[name] = make(map[string]string)
+			}
+			pending[name][value] = doc.Key
+		}
+	}
+
+	if len(ve) > 0 {
+		return errors.Wrap(op, errors.EConflict, ve)
+	}
+
+	return nil
+}
+
+// indexUnique updates the unique indexes of the collection
+// to hold the values of `doc` rather than those of `old`,
+// either of which may be nil
+func (c *Collection) indexUnique(ctx context.Context, old, doc *types.Document) error {
+	for name, idx := range c.Unique {
+		if old != nil {
+			if f, ok := old.Fields[name]; ok && f.Value != nil {
+				value := types.UniqueKey(f.Value)
+
+				rec, err := idx.Get(ctx, value)
+				if err == nil && rec.Value == old.Key {
+					if err := idx.Delete(ctx, value); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		if doc != nil {
+			if f, ok := doc.Fields[name]; ok && f.Value != nil {
+				if err := idx.Insert(ctx, types.UniqueKey(f.Value), doc.Key, ""); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
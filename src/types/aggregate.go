@@ -0,0 +1,214 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// An Operator compares a field to a value
+type Operator string
+
+// Comparison operators
+const (
+	Eq Operator = "="
+	Ne          = "!="
+	Lt          = "<"
+	Le          = "<="
+	Gt          = ">"
+	Ge          = ">="
+)
+
+// A Condition tests the field at Path against Value
+type Condition struct {
+	Path  []string
+	Op    Operator
+	Value Field
+}
+
+// Match reports whether the document satisfies the
+// condition. A document never satisfies a condition on a
+// field it does not have, and values of different types
+// are only ever unequal. Strings are compared to Timestamp
+// and Bytes fields as the values they represent.
+func (c Condition) Match(doc Document) bool {
+	f, ok := doc.Get(c.Path...)
+	if !ok {
+		return false
+	}
+
+	value := literal(c.Value, f.Type)
+
+	switch c.Op {
+	case Eq:
+		return f.Equal(value)
+	case Ne:
+		return !f.Equal(value)
+	}
+
+	cmp, err := f.Compare(value)
+	if err != nil {
+		return false
+	}
+
+	switch c.Op {
+	case Lt:
+		return cmp < 0
+	case Le:
+		return cmp <= 0
+	case Gt:
+		return cmp > 0
+	case Ge:
+		return cmp >= 0
+	default:
+		return false
+	}
+}
+
+func (c Condition) String() string {
+	return fmt.Sprintf("%s %s %v", strings.Join(c.Path, "."), c.Op, c.Value.Value)
+}
+
+// A Filter is a conjunction of conditions. The empty
+// filter matches every document.
+type Filter []Condition
+
+// Match reports whether the document satisfies every
+// condition in the filter
+func (f Filter) Match(doc Document) bool {
+	for _, c := range f {
+		if !c.Match(doc) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// An AggregateFunc reduces the values of a field across a
+// set of documents to a single value
+type AggregateFunc string
+
+// Aggregate functions
+const (
+	Count AggregateFunc = "COUNT"
+	Sum                 = "SUM"
+	Avg                 = "AVG"
+	Min                 = "MIN"
+	Max                 = "MAX"
+)
+
+// An Aggregate applies Func to the field at Path. A COUNT
+// with an empty path counts documents rather than values.
+type Aggregate struct {
+	Func AggregateFunc
+	Path []string
+}
+
+func (a Aggregate) String() string {
+	path := strings.Join(a.Path, ".")
+	if path == "" {
+		path = "*"
+	}
+
+	return fmt.Sprintf("%s(%s)", a.Func, path)
+}
+
+// An AggregateQuery computes aggregates over the documents
+// that match Where, grouped by the values of the fields in
+// GroupBy
+type AggregateQuery struct {
+	Aggregates []Aggregate
+	Where      Filter
+	GroupBy    [][]string
+}
+
+// An Accumulator computes the value of an aggregate from
+// the documents added to it
+type Accumulator struct {
+	agg   Aggregate
+	count int
+	sum   big.Rat
+	best  *Field
+}
+
+// NewAccumulator returns an accumulator for `agg`
+func NewAccumulator(agg Aggregate) *Accumulator {
+	return &Accumulator{agg: agg}
+}
+
+// Add the document to the aggregate. Documents without the
+// aggregated field are ignored. An error is returned if the
+// field cannot be aggregated, e.g. when summing Strings or
+// taking the minimum of values of different types.
+func (a *Accumulator) Add(doc Document) error {
+	if a.agg.Func == Count && len(a.agg.Path) == 0 {
+		a.count++
+		return nil
+	}
+
+	f, ok := doc.Get(a.agg.Path...)
+	if !ok {
+		return nil
+	}
+
+	switch a.agg.Func {
+	case Count:
+		a.count++
+
+	case Sum, Avg:
+		n, ok := toRat(f.Value)
+		if !f.IsType(Number) || !ok {
+			return fmt.Errorf("%s: Expected value of type %s but got %s", a.agg, Number, f.Type)
+		}
+
+		a.count++
+		a.sum.Add(&a.sum, n)
+
+	case Min, Max:
+		if a.best == nil {
+			if _, err := f.Compare(f); err != nil {
+				return fmt.Errorf("%s: %w", a.agg, err)
+			}
+
+			a.best = &f
+			return nil
+		}
+
+		cmp, err := f.Compare(*a.best)
+		if err != nil {
+			return fmt.Errorf("%s: %w", a.agg, err)
+		}
+
+		if (a.agg.Func == Min && cmp < 0) || (a.agg.Func == Max && cmp > 0) {
+			a.best = &f
+		}
+
+	default:
+		return fmt.Errorf("Unknown aggregate function %s", a.agg.Func)
+	}
+
+	return nil
+}
+
+// Value returns the value of the aggregate. SUM and AVG are
+// computed exactly and returned as Numbers. SUM of no values
+// is 0, while AVG, MIN and MAX of no values are nil.
+func (a *Accumulator) Value() interface{} {
+	switch a.agg.Func {
+	case Count:
+		return a.count
+	case Sum:
+		return ratToNumber(&a.sum)
+	case Avg:
+		if a.count == 0 {
+			return nil
+		}
+		return ratToNumber(new(big.Rat).Quo(&a.sum, big.NewRat(int64(a.count), 1)))
+	default:
+		if a.best == nil {
+			return nil
+		}
+		return a.best.Value
+	}
+}
@@ -0,0 +1,105 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestFieldCompare(t *testing.T) {
+	for i, test := range []struct {
+		a, b interface{}
+		want int
+		err  bool
+	}{
+		{1.0, 2.0, -1, false},
+		{2, 1.5, 1, false},
+		{"b", "a", 1, false},
+		{false, true, -1, false},
+		{true, true, 0, false},
+		{1.0, "1", 0, true},
+		{[]interface{}{}, []interface{}{}, 0, true},
+		{time.Unix(1, 0), time.Unix(2, 0), -1, false},
+		{time.Unix(1, 0).UTC(), time.Unix(1, 0), 0, false},
+		{[]byte{1, 2}, []byte{1}, 1, false},
+		{time.Unix(1, 0), "1970-01-01T00:00:01Z", 0, true},
+	} {
+		got, err := newField(test.a).Compare(newField(test.b))
+		if (err != nil) != test.err {
+			t.Errorf("%d: Unexpected error value %v", i, err)
+		}
+
+		if got != test.want {
+			t.Errorf("%d: Want=%d Got=%d", i, test.want, got)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	doc := NewDoc("k").Set(map[string]interface{}{
+		"age":     30.0,
+		"alive":   true,
+		"address": map[string]interface{}{"city": "Oslo"},
+		"born":    time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC),
+		"data":    []byte("hi"),
+	})
+
+	for i, test := range []struct {
+		filter Filter
+		want   bool
+	}{
+		{Filter{}, true},
+		{Filter{{[]string{"age"}, Ge, newField(30.0)}}, true},
+		{Filter{{[]string{"age"}, Lt, newField(30.0)}}, false},
+		{Filter{{[]string{"age"}, Eq, newField("30")}}, false},
+		{Filter{{[]string{"age"}, Ne, newField("30")}}, true},
+		{Filter{{[]string{"address", "city"}, Eq, newField("Oslo")}}, true},
+		{Filter{{[]string{"alive"}, Eq, newField(true)}, {[]string{"age"}, Gt, newField(40.0)}}, false},
+		{Filter{{[]string{"missing"}, Ne, newField(1.0)}}, false},
+		{Filter{{[]string{"born"}, Lt, newField("2000-01-01T00:00:00+01:00")}}, true},
+		{Filter{{[]string{"born"}, Eq, newField("1990-01-02T01:00:00+01:00")}}, true},
+		{Filter{{[]string{"born"}, Eq, newField("yesterday")}}, false},
+		{Filter{{[]string{"data"}, Eq, newField("aGk=")}}, true},
+	} {
+		if got := test.filter.Match(doc); got != test.want {
+			t.Errorf("%d: %v Want=%v Got=%v", i, test.filter, test.want, got)
+		}
+	}
+}
+
+func TestAccumulator(t *testing.T) {
+	var docs []Document
+	for _, v := range []interface{}{3.0, 1.0, 2.0} {
+		docs = append(docs, NewDoc("k").Set(map[string]interface{}{"n": v}))
+	}
+	docs = append(docs, NewDoc("k"))
+
+	for i, test := range []struct {
+		agg  Aggregate
+		want interface{}
+	}{
+		{Aggregate{Count, nil}, 4},
+		{Aggregate{Count, []string{"n"}}, 3},
+		{Aggregate{Sum, []string{"n"}}, json.Number("6")},
+		{Aggregate{Avg, []string{"n"}}, json.Number("2")},
+		{Aggregate{Min, []string{"n"}}, 1.0},
+		{Aggregate{Max, []string{"n"}}, 3.0},
+		{Aggregate{Max, []string{"missing"}}, nil},
+	} {
+		acc := NewAccumulator(test.agg)
+		for _, doc := range docs {
+			if err := acc.Add(doc); err != nil {
+				t.Fatalf("%d: Unexpected error: %s", i, err)
+			}
+		}
+
+		if got := acc.Value(); got != test.want {
+			t.Errorf("%d: %s Want=%v Got=%v", i, test.agg, test.want, got)
+		}
+	}
+
+	acc := NewAccumulator(Aggregate{Sum, []string{"s"}})
+	if err := acc.Add(NewDoc("k").Set(map[string]interface{}{"s": "x"})); err == nil {
+		t.Errorf("Expected error summing String values")
+	}
+}
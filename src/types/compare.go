@@ -0,0 +1,107 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Compare returns -1, 0 or 1 if f is less than, equal to
+// or greater than other. Numbers are compared exactly,
+// Strings lexicographically, Booleans with false ordered
+// before true, Timestamps chronologically and Bytes
+// lexicographically by byte. An error is returned if the fields
+// have different types or are not of one of these types.
+func (f Field) Compare(other Field) (int, error) {
+	if f.Type != other.Type {
+		return 0, fmt.Errorf("Cannot compare %s to %s", f.Type, other.Type)
+	}
+
+	switch f.Type {
+	case Number:
+		cmp, ok := compareNumbers(f.Value, other.Value)
+		if !ok {
+			return 0, fmt.Errorf("Invalid Number values %v and %v", f.Value, other.Value)
+		}
+
+		return cmp, nil
+
+	case String:
+		return strings.Compare(f.Value.(string), other.Value.(string)), nil
+
+	case Boolean:
+		a, b := f.Value.(bool), other.Value.(bool)
+		switch {
+		case a == b:
+			return 0, nil
+		case !a:
+			return -1, nil
+		default:
+			return 1, nil
+		}
+
+	case Timestamp:
+		a, b := f.Value.(time.Time), other.Value.(time.Time)
+		switch {
+		case a.Before(b):
+			return -1, nil
+		case a.After(b):
+			return 1, nil
+		default:
+			return 0, nil
+		}
+
+	case Bytes:
+		return bytes.Compare(f.Value.([]byte), other.Value.([]byte)), nil
+
+	default:
+		return 0, fmt.Errorf("Values of type %s are not ordered", f.Type)
+	}
+}
+
+// Equal reports whether f and other have the same type and
+// value
+func (f Field) Equal(other Field) bool {
+	if cmp, err := f.Compare(other); err == nil {
+		return cmp == 0
+	}
+
+	return f.Type == other.Type && reflect.DeepEqual(f.Value, other.Value)
+}
+
+// toFloat converts the value of a Number field to a
+// float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case uint:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// literal converts a String to type t if t is Timestamp or
+// Bytes, which are written as Strings in queries. Other
+// fields are returned unchanged.
+func literal(f Field, t Type) Field {
+	if f.IsType(String) && (t == Timestamp || t == Bytes) {
+		// f is left unchanged if it cannot be converted
+		f.ToType(t)
+	}
+
+	return f
+}
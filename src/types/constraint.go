@@ -0,0 +1,434 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Formats maps the names of the built-in string formats to
+// functions that report whether a string has that format
+var Formats = map[string]func(string) bool{
+	"email":     isEmail,
+	"uuid":      uuidPattern.MatchString,
+	"date-time": isDateTime,
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func isEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+func isDateTime(s string) bool {
+	_, err := time.Parse(time.RFC3339, s)
+	return err == nil
+}
+
+// WithPattern constrains a String field to values that
+// match the regular expression `expr`
+func WithPattern(expr string) SchemaFieldOption {
+	return func(sf *SchemaField) {
+		sf.Pattern = expr
+	}
+}
+
+// WithFormat constrains a String field to values of one of
+// the built-in Formats
+func WithFormat(name string) SchemaFieldOption {
+	return func(sf *SchemaField) {
+		sf.Format = name
+	}
+}
+
+// WithEnum constrains a field to the given values
+func WithEnum(values ...interface{}) SchemaFieldOption {
+	return func(sf *SchemaField) {
+		sf.Enum = values
+	}
+}
+
+// WithMinimum sets the smallest value of a Number field
+func WithMinimum(min json.Number) SchemaFieldOption {
+	return func(sf *SchemaField) {
+		sf.Minimum = &min
+	}
+}
+
+// WithMaximum sets the largest value of a Number field
+func WithMaximum(max json.Number) SchemaFieldOption {
+	return func(sf *SchemaField) {
+		sf.Maximum = &max
+	}
+}
+
+// Integer constrains a Number field to whole numbers
+func Integer(sf *SchemaField) {
+	sf.Integer = true
+}
+
+// UniqueItems constrains the elements of an Array field to
+// be distinct
+func UniqueItems(sf *SchemaField) {
+	sf.UniqueItems = true
+}
+
+// Unique constrains the values of a field to be distinct
+// across the documents of a collection. Only top-level
+// fields of scalar types may be unique.
+func Unique(sf *SchemaField) {
+	sf.Unique = true
+}
+
+// WithElements constrains every element of an Array field
+// to a value of type `t` that satisfies `opts`, e.g. Numbers
+// in a range or Arrays of their own
+func WithElements(t Type, opts ...SchemaFieldOption) SchemaFieldOption {
+	el := SchemaField{Type: t, Required: true}
+	for _, opt := range opts {
+		opt(&el)
+	}
+
+	return func(sf *SchemaField) {
+		sf.ElementType = &t
+		sf.Elements = &el
+	}
+}
+
+// elementField returns the schema field that every element
+// of an Array field must satisfy, if any
+func (sf SchemaField) elementField() (SchemaField, bool) {
+	if sf.Elements != nil {
+		return *sf.Elements, true
+	}
+
+	if sf.ElementType == nil {
+		return SchemaField{}, false
+	}
+
+	el := SchemaField{Type: *sf.ElementType, Required: true}
+	if el.Type.Is(Object) {
+		el.Schema = sf.Schema
+	}
+
+	return el, true
+}
+
+// An ElementError is the error of the element at Index in
+// an Array
+type ElementError struct {
+	Index int
+	Err   error
+}
+
+func (e ElementError) Error() string {
+	return fmt.Sprintf("Element %d: %s", e.Index, e.Err)
+}
+
+func (e ElementError) Unwrap() error {
+	return e.Err
+}
+
+// duplicates returns the indices of the elements of `arr`
+// that are equal to an earlier element
+func duplicates(arr []interface{}) []int {
+	var (
+		out  []int
+		seen = make(map[string]bool)
+	)
+
+	for i, v := range arr {
+		key := UniqueKey(v)
+		if seen[key] {
+			out = append(out, i)
+		}
+		seen[key] = true
+	}
+
+	return out
+}
+
+// UniqueKey returns a string that is equal for equal
+// values. Numbers are keyed by their exact value, such that
+// 1 and 1.0 are equal, and Timestamps by the instant they
+// represent.
+func UniqueKey(v interface{}) string {
+	if r, ok := toRat(v); ok {
+		return "n:" + r.RatString()
+	}
+
+	if t, ok := v.(time.Time); ok {
+		return "t:" + t.UTC().Format(time.RFC3339Nano)
+	}
+
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// patterns caches compiled patterns by their source
+var patterns sync.Map
+
+func compilePattern(expr string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns.Store(expr, re)
+	return re, nil
+}
+
+// checkConstraints reports constraints that are invalid or
+// do not apply to the type of the field
+func (sf SchemaField) checkConstraints() []error {
+	var errs []error
+
+	if (sf.Pattern != "" || sf.Format != "") && !sf.Type.Is(String) {
+		errs = append(errs, fmt.Errorf("Patterns and formats only apply to fields of type String"))
+	}
+
+	if sf.Pattern != "" {
+		if _, err := compilePattern(sf.Pattern); err != nil {
+			errs = append(errs, fmt.Errorf("Invalid pattern: %w", err))
+		}
+	}
+
+	if _, ok := Formats[sf.Format]; sf.Format != "" && !ok {
+		errs = append(errs, fmt.Errorf("Unknown format: %s", sf.Format))
+	}
+
+	errs = append(errs, sf.checkReference()...)
+
+	if sf.Unique {
+		if sf.Type.Is(Object) || sf.Type.Is(Map) || sf.Type.Is(Array) {
+			errs = append(errs, fmt.Errorf("Unique constraints do not apply to fields of type %s", sf.Type))
+		}
+
+		// Every document that leaves out the field would
+		// share the default value
+		if sf.HasDefault() {
+			errs = append(errs, fmt.Errorf("Unique fields cannot have a default value"))
+		}
+	}
+
+	if sf.hasNestedUnique() {
+		errs = append(errs, fmt.Errorf("Unique constraints only apply to top-level fields"))
+	}
+
+	if sf.UniqueItems && !sf.Type.Is(Array) {
+		errs = append(errs, fmt.Errorf("Unique items only apply to fields of type Array"))
+	}
+
+	if sf.Elements != nil {
+		for _, err := range sf.Elements.checkConstraints() {
+			errs = append(errs, fmt.Errorf("Elements: %w", err))
+		}
+	}
+
+	if (sf.Minimum != nil || sf.Maximum != nil || sf.Integer) && !sf.Type.Is(Number) {
+		errs = append(errs, fmt.Errorf("Value ranges and integer constraints only apply to fields of type Number"))
+	}
+
+	for _, bound := range []*json.Number{sf.Minimum, sf.Maximum} {
+		if bound == nil {
+			continue
+		}
+
+		if _, ok := toRat(*bound); !ok {
+			errs = append(errs, fmt.Errorf("Invalid bound %q", *bound))
+		}
+	}
+
+	if cmp, ok := compareNumbers(sf.minimum(), sf.maximum()); ok && cmp > 0 {
+		errs = append(errs, fmt.Errorf("Minimum %v is greater than maximum %v", *sf.Minimum, *sf.Maximum))
+	}
+
+	for _, v := range sf.Enum {
+		if t := GetDataType(v); t != sf.Type {
+			errs = append(errs, fmt.Errorf("Enum value %v has type %s, expected %s", v, t, sf.Type))
+		}
+	}
+
+	return errs
+}
+
+// hasNestedUnique reports whether a field of the schema or
+// the elements of the field is unique
+func (sf SchemaField) hasNestedUnique() bool {
+	if sf.Elements != nil && (sf.Elements.Unique || sf.Elements.hasNestedUnique()) {
+		return true
+	}
+
+	if sf.Schema == nil {
+		return false
+	}
+
+	for _, field := range sf.Schema.fields {
+		if field.Unique || field.hasNestedUnique() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateConstraints returns the constraints of the schema
+// field that `v` violates. `v` must have the field's type.
+func (sf SchemaField) validateConstraints(v interface{}) []error {
+	var errs []error
+
+	if len(sf.Enum) > 0 && !sf.inEnum(v) {
+		errs = append(errs, fmt.Errorf("Expected one of %s, Got %v", sf.enumString(), v))
+	}
+
+	switch sf.Type {
+	case String:
+		s, _ := v.(string)
+
+		if sf.Pattern != "" {
+			re, err := compilePattern(sf.Pattern)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("Invalid pattern: %w", err))
+			} else if !re.MatchString(s) {
+				errs = append(errs, fmt.Errorf("Expected value matching %s, Got %q", sf.Pattern, s))
+			}
+		}
+
+		if isFormat, ok := Formats[sf.Format]; ok && !isFormat(s) {
+			errs = append(errs, fmt.Errorf("Expected value of format %s, Got %q", sf.Format, s))
+		}
+
+	case Number:
+		if sf.Integer && !isInteger(v) {
+			errs = append(errs, fmt.Errorf("Expected an integer, Got %v", v))
+		}
+
+		if cmp, ok := compareNumbers(v, sf.minimum()); ok && cmp < 0 {
+			errs = append(errs, fmt.Errorf("Expected a value of at least %v, Got %v", *sf.Minimum, v))
+		}
+
+		if cmp, ok := compareNumbers(v, sf.maximum()); ok && cmp > 0 {
+			errs = append(errs, fmt.Errorf("Expected a value of at most %v, Got %v", *sf.Maximum, v))
+		}
+	}
+
+	return errs
+}
+
+// minimum returns the smallest value of a Number field, or
+// nil if it has none
+func (sf SchemaField) minimum() interface{} {
+	if sf.Minimum == nil {
+		return nil
+	}
+
+	return *sf.Minimum
+}
+
+// maximum returns the largest value of a Number field, or
+// nil if it has none
+func (sf SchemaField) maximum() interface{} {
+	if sf.Maximum == nil {
+		return nil
+	}
+
+	return *sf.Maximum
+}
+
+func (sf SchemaField) inEnum(v interface{}) bool {
+	for _, e := range sf.Enum {
+		if GetDataType(e) == Number {
+			if cmp, ok := compareNumbers(e, v); ok && cmp == 0 {
+				return true
+			}
+			continue
+		}
+
+		if reflect.DeepEqual(e, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (sf SchemaField) enumString() string {
+	values := make([]string, len(sf.Enum))
+	for i, v := range sf.Enum {
+		if s, ok := v.(string); ok {
+			values[i] = quote(s)
+		} else {
+			values[i] = fmt.Sprint(v)
+		}
+	}
+
+	return strings.Join(values, ",")
+}
+
+// constraintStrings returns the constraints of the field as
+// they are written in a schema, e.g. String(1,10, format="email")
+func (sf SchemaField) constraintStrings() []string {
+	var out []string
+
+	if sf.Min != nil || sf.Max != nil {
+		var sb strings.Builder
+		if sf.Min != nil {
+			sb.WriteString(fmt.Sprint(*sf.Min))
+		}
+
+		if sf.Max != nil {
+			sb.WriteString(fmt.Sprintf(",%d", *sf.Max))
+		}
+		out = append(out, sb.String())
+	}
+
+	if sf.Minimum != nil || sf.Maximum != nil {
+		var sb strings.Builder
+		if sf.Minimum != nil {
+			sb.WriteString(fmt.Sprint(*sf.Minimum))
+		}
+		sb.WriteString("..")
+		if sf.Maximum != nil {
+			sb.WriteString(fmt.Sprint(*sf.Maximum))
+		}
+		out = append(out, sb.String())
+	}
+
+	if sf.Integer {
+		out = append(out, "integer")
+	}
+
+	if sf.UniqueItems {
+		out = append(out, "unique")
+	}
+
+	if sf.Pattern != "" {
+		out = append(out, "pattern="+quote(sf.Pattern))
+	}
+
+	if sf.Format != "" {
+		out = append(out, "format="+quote(sf.Format))
+	}
+
+	return out
+}
+
+// quote quotes a string the way the query language does,
+// which has no escape sequences
+func quote(s string) string {
+	if strings.Contains(s, `"`) {
+		return "'" + s + "'"
+	}
+
+	return `"` + s + `"`
+}
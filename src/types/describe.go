@@ -0,0 +1,84 @@
+package types
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// A FieldDescription is the structured form of a schema
+// field, as returned by DESCRIBE
+type FieldDescription struct {
+	Name     string      `json:"name,omitempty"`
+	Type     Type        `json:"type"`
+	Required bool        `json:"required"`
+	Unique   bool        `json:"unique,omitempty"`
+	Default  interface{} `json:"default,omitempty"`
+	Computed string      `json:"computed,omitempty"`
+	Ref      *Reference  `json:"ref,omitempty"`
+
+	// Constraints
+	MinLength   *int          `json:"minLength,omitempty"` // Of a String or an Array
+	MaxLength   *int          `json:"maxLength,omitempty"` // Of a String or an Array
+	Minimum     *json.Number  `json:"minimum,omitempty"`
+	Maximum     *json.Number  `json:"maximum,omitempty"`
+	Integer     bool          `json:"integer,omitempty"`
+	Pattern     string        `json:"pattern,omitempty"`
+	Format      string        `json:"format,omitempty"`
+	Enum        []interface{} `json:"enum,omitempty"`
+	UniqueItems bool          `json:"uniqueItems,omitempty"`
+
+	// Fields describes the schema of an Object, and Elements
+	// the elements of an Array
+	Fields   []FieldDescription `json:"fields,omitempty"`
+	Elements *FieldDescription  `json:"elements,omitempty"`
+}
+
+// Describe returns the fields of the schema in order of
+// their names
+func (s Schema) Describe() []FieldDescription {
+	var names []string
+	for name := range s.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := make([]FieldDescription, len(names))
+	for i, name := range names {
+		out[i] = s.fields[name].describe(name)
+	}
+
+	return out
+}
+
+func (sf SchemaField) describe(name string) FieldDescription {
+	fd := FieldDescription{
+		Name:        name,
+		Type:        sf.Type,
+		Required:    sf.Required,
+		Unique:      sf.Unique,
+		Default:     sf.DefaultValue,
+		Ref:         sf.Ref,
+		MinLength:   sf.Min,
+		MaxLength:   sf.Max,
+		Minimum:     sf.Minimum,
+		Maximum:     sf.Maximum,
+		Integer:     sf.Integer,
+		Pattern:     sf.Pattern,
+		Format:      sf.Format,
+		Enum:        sf.Enum,
+		UniqueItems: sf.UniqueItems,
+	}
+
+	if sf.Computed != nil {
+		fd.Computed = sf.Computed.String()
+	}
+
+	if el, ok := sf.elementField(); ok && sf.Type.Is(Array) {
+		elements := el.describe("")
+		fd.Elements = &elements
+	} else if sf.Schema != nil {
+		fd.Fields = sf.Schema.Describe()
+	}
+
+	return fd
+}
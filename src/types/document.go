@@ -3,11 +3,9 @@ package types
 import (
 	"crypto/sha256"
 	"encoding/base64"
-	"encoding/gob"
 	"encoding/json"
 	"fmt"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -20,17 +18,53 @@ type Document struct {
 	CreatedAt    time.Time
 	LastModified time.Time
 	Deleted      bool
+
+	// Version identifies the revision of the document. It
+	// changes on every write and may be used to reject writes
+	// based on a stale read.
+	Version string
+
+	// Revision is incremented every time the document is
+	// written, starting at 1
+	Revision int
+
+	// ExpiresAt is the time after which the document is no
+	// longer visible. The document never expires if it is
+	// the zero time.
+	ExpiresAt time.Time
+}
+
+// A DocumentOption sets the metadata of a document when it
+// is written
+type DocumentOption func(*Document)
+
+// WithTTL makes a document expire `ttl` after it is written
+func WithTTL(ttl time.Duration) DocumentOption {
+	return func(d *Document) {
+		d.ExpiresAt = time.Now().Add(ttl)
+	}
 }
 
+// Expired reports whether the document has expired at time
+// `now`
+func (d Document) Expired(now time.Time) bool {
+	return !d.ExpiresAt.IsZero() && !now.Before(d.ExpiresAt)
+}
+
+// Hash returns a digest of the document's fields. Equal
+// fields always produce the same hash.
 func (d Document) Hash() string {
-	s := sha256.New()
-	enc := gob.NewEncoder(s)
-	
-	for _, field := range d.Fields {
-		enc.Encode(field.Value)
+	values := make(map[string]interface{})
+	for name, field := range d.Fields {
+		values[name] = field.Value
 	}
 
-	return base64.StdEncoding.EncodeToString(s.Sum(nil))
+	// encoding/json sorts map keys, which makes the encoding
+	// independent of map iteration order
+	b, _ := json.Marshal(values)
+	sum := sha256.Sum256(b)
+
+	return base64.StdEncoding.EncodeToString(sum[:])
 }
 
 // Set the values of one or more fields whose type are
@@ -59,6 +93,8 @@ func (d Document) Update(fields map[string]interface{}) Document {
 		c.Fields[name] = newField(value)
 	}
 
+	c.LastModified = time.Now()
+
 	return c
 }
 
@@ -91,21 +127,6 @@ func (d Document) Get(fieldPath ...string) (Field, bool) {
 	return f, true
 }
 
-func (d Document) Select(selectFn ...FieldSelector) map[string]interface{} {
-	out := make(map[string]interface{})
-
-	for _, selectFn := range selectFn {
-		name, field, ok := selectFn(d)
-		if !ok {
-			out[name] = fmt.Errorf("Value at fieldpath %s does not exist", name)
-		} else {
-			out[name] = field
-		}
-	}
-
-	return out
-}
-
 func (d Document) String() string {
 	s, _ := prettify(d.Fields)
 	return fmt.Sprintf("%s=%s", d.Key, s)
@@ -180,7 +201,7 @@ func (f *Field) ToObject() error {
 
 // TODO: Test
 func (f *Field) ToNumber() error {
-	v, err := strconv.ParseFloat(f.Value.(string), 64)
+	v, err := ParseNumber(f.Value.(string))
 	if err != nil {
 		return err
 	}
@@ -203,6 +224,42 @@ func (f *Field) ToBoolean() error {
 	return nil
 }
 
+// ToTimestamp converts a String field holding an RFC 3339
+// date and time to a Timestamp
+func (f *Field) ToTimestamp() error {
+	s, ok := f.Value.(string)
+	if !ok {
+		return fmt.Errorf("TypeConversionError: Cannot convert %s to %s", f.Type, Timestamp)
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return err
+	}
+
+	f.Value = t
+	f.Type = Timestamp
+	return nil
+}
+
+// ToBytes converts a String field holding base64 encoded
+// data to Bytes
+func (f *Field) ToBytes() error {
+	s, ok := f.Value.(string)
+	if !ok {
+		return fmt.Errorf("TypeConversionError: Cannot convert %s to %s", f.Type, Bytes)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+
+	f.Value = b
+	f.Type = Bytes
+	return nil
+}
+
 // TODO: implement
 func (f *Field) ToArray() error {
 	return nil
@@ -230,14 +287,20 @@ func (f *Field) Validate(name string, schemaField SchemaField) []error {
 		return f.Validate(name, schemaField)
 	}
 
-	if f.IsType(Object) {
+	if f.IsType(Object) && schemaField.Schema != nil {
 		obj := f.Value.(map[string]interface{})
-		r := NewDoc("k")
-		r.Set(obj)
+		r := NewDoc("k").Set(obj)
 		objErrs := schemaField.Schema.Validate(r)
 
 		if objErrs != nil {
 			errs = append(errs, objErrs)
+		} else {
+			// Keep the values converted by the schema
+			out := make(map[string]interface{}, len(r.Fields))
+			for name, field := range r.Fields {
+				out[name] = field.Value
+			}
+			f.Value = out
 		}
 	}
 
@@ -248,11 +311,23 @@ func (f *Field) Validate(name string, schemaField SchemaField) []error {
 			return errs
 		}
 
-		if schemaField.ElementType != nil {
-			for _, e := range arr {
-				if got := GetDataType(e); got != *schemaField.ElementType {
-					errs = append(errs, fmt.Errorf("Expected array element of type %s but got %s", *schemaField.ElementType, got))
+		if el, ok := schemaField.elementField(); ok {
+			out := make([]interface{}, len(arr))
+			for i, v := range arr {
+				e := newField(v)
+				if elErrs := e.Validate(fmt.Sprintf("%s[%d]", name, i), el); elErrs != nil {
+					errs = append(errs, ElementError{Index: i, Err: FieldValidationError(elErrs)})
 				}
+				out[i] = e.Value
+			}
+
+			arr = out
+			f.Value = out
+		}
+
+		if schemaField.UniqueItems {
+			for _, i := range duplicates(arr) {
+				errs = append(errs, ElementError{Index: i, Err: fmt.Errorf("Duplicate element %v", arr[i])})
 			}
 		}
 
@@ -281,6 +356,8 @@ func (f *Field) Validate(name string, schemaField SchemaField) []error {
 		}
 	}
 
+	errs = append(errs, schemaField.validateConstraints(f.Value)...)
+
 	if len(errs) != 0 {
 		return errs
 	}
@@ -330,6 +407,18 @@ func (f *Field) ToType(t Type) error {
 			return err
 		}
 
+	case Timestamp:
+		err := f.ToTimestamp()
+		if err != nil {
+			return err
+		}
+
+	case Bytes:
+		err := f.ToBytes()
+		if err != nil {
+			return err
+		}
+
 	case Array:
 		// TODO: Implmement
 
@@ -353,52 +442,6 @@ func (f *Field) IsOneOf(ts ...Type) bool {
 	return false
 }
 
-type FieldSelector func(Document) (string, Field, bool)
-
-// TODO: test
-func MakeFieldSelectors(selectors ...string) []FieldSelector {
-	out := []FieldSelector{}
-
-	for _, selector := range selectors {
-		name := selector
-
-		out = append(out, func(r Document) (string, Field, bool) {
-			fieldPath := strings.Split(name, ".")
-			f, ok := r.Fields[fieldPath[0]]
-
-			if !ok {
-				return name, f, false
-			}
-
-			if len(fieldPath) > 1 {
-				for _, fieldName := range fieldPath[1:] {
-					if !f.IsOneOf(Object, Map) {
-						return name, f, false
-					}
-
-					ob, ok := f.Value.(map[string]interface{})
-					if !ok {
-						return name, f, false
-					}
-
-					v, ok := ob[fieldName]
-					if !ok {
-						return name, f, false
-					}
-
-					f = newField(v)
-				}
-
-			}
-
-			return name, f, true
-		})
-
-	}
-
-	return out
-}
-
 func prettify(v interface{}) (string, error) {
 	b, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
@@ -0,0 +1,367 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExprKind is the kind of an expression
+type ExprKind string
+
+// Expression kinds
+const (
+	LiteralExpr ExprKind = "Literal" // A constant value
+	FuncExpr             = "Func"    // A call to one of Funcs, e.g. uuid()
+	VarExpr              = "Var"     // A variable, e.g. $client
+	FieldExpr            = "Field"   // The value of another field
+	SumExpr              = "Sum"     // The sum or concatenation of Args
+)
+
+// An Expr computes the value of a field when a document is
+// written, e.g. uuid(), $client, now() or
+// first + " " + last
+type Expr struct {
+	Kind  ExprKind
+	Name  string      // Function, variable or field name
+	Value interface{} // Value of a literal
+	Args  []Expr      // Operands of a sum
+}
+
+// Funcs are the functions that may be called in an Expr,
+// along with the type of their result
+var Funcs = map[string]struct {
+	Type Type
+	Call func() interface{}
+}{
+	"uuid": {String, func() interface{} { return uuid.New().String() }},
+	"now":  {Timestamp, func() interface{} { return time.Now().UTC() }},
+}
+
+// Vars are the variables that may be used in an Expr
+var Vars = map[string]Type{
+	"client": String,
+}
+
+type clientKey struct{}
+
+// WithClient returns a context that identifies the client
+// that makes a request, which is the value of $client
+func WithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// ClientFrom returns the client set by WithClient, if any
+func ClientFrom(ctx context.Context) (string, bool) {
+	client, ok := ctx.Value(clientKey{}).(string)
+	return client, ok
+}
+
+// Lit returns an expression with the constant value `v`
+func Lit(v interface{}) Expr {
+	return Expr{Kind: LiteralExpr, Value: v}
+}
+
+// Call returns an expression that calls the function `name`
+func Call(name string) Expr {
+	return Expr{Kind: FuncExpr, Name: name}
+}
+
+// Var returns an expression with the value of the variable
+// `name`
+func Var(name string) Expr {
+	return Expr{Kind: VarExpr, Name: name}
+}
+
+// FieldRef returns an expression with the value of the field
+// `name`
+func FieldRef(name string) Expr {
+	return Expr{Kind: FieldExpr, Name: name}
+}
+
+// Plus returns an expression that adds Numbers or
+// concatenates Strings
+func Plus(args ...Expr) Expr {
+	return Expr{Kind: SumExpr, Args: args}
+}
+
+// WithComputed makes the value of a field the result of `e`.
+// Expressions that depend on other fields or the time are
+// evaluated on every write. Other expressions, such as
+// uuid() and $client, only fill in missing values, such
+// that identifiers and authors are kept when a document is
+// updated.
+func WithComputed(e Expr) SchemaFieldOption {
+	return func(sf *SchemaField) {
+		sf.Computed = &e
+	}
+}
+
+func (e Expr) String() string {
+	switch e.Kind {
+	case LiteralExpr:
+		if s, ok := e.Value.(string); ok {
+			return quote(s)
+		}
+		return fmt.Sprint(e.Value)
+	case FuncExpr:
+		return e.Name + "()"
+	case VarExpr:
+		return "$" + e.Name
+	case SumExpr:
+		args := make([]string, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = arg.String()
+		}
+		return strings.Join(args, " + ")
+	default:
+		return e.Name
+	}
+}
+
+// fields returns the names of the fields that `e` refers to
+func (e Expr) fields() []string {
+	switch e.Kind {
+	case FieldExpr:
+		return []string{e.Name}
+	case SumExpr:
+		var out []string
+		for _, arg := range e.Args {
+			out = append(out, arg.fields()...)
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// volatile reports whether `e` depends on other fields or
+// the time, in which case it is evaluated on every write
+func (e Expr) volatile() bool {
+	switch e.Kind {
+	case FieldExpr:
+		return true
+	case FuncExpr:
+		return e.Name == "now"
+	case SumExpr:
+		for _, arg := range e.Args {
+			if arg.volatile() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// typeOf returns the type of the value of `e` in a document
+// with the given fields
+func (e Expr) typeOf(fields map[string]SchemaField) (Type, error) {
+	switch e.Kind {
+	case LiteralExpr:
+		return GetDataType(e.Value), nil
+	case FuncExpr:
+		fn, ok := Funcs[e.Name]
+		if !ok {
+			return Unknown, fmt.Errorf("Unknown function %s()", e.Name)
+		}
+		return fn.Type, nil
+	case VarExpr:
+		t, ok := Vars[e.Name]
+		if !ok {
+			return Unknown, fmt.Errorf("Unknown variable $%s", e.Name)
+		}
+		return t, nil
+	case FieldExpr:
+		field, ok := fields[e.Name]
+		if !ok {
+			return Unknown, fmt.Errorf("Unknown field %s", e.Name)
+		}
+
+		// The type of a computed field may not be declared
+		if (field.Type == "" || field.Type == Unknown) && field.Computed != nil && !field.Computed.volatile() {
+			return field.Computed.typeOf(fields)
+		}
+		return field.Type, nil
+	case SumExpr:
+		var t Type
+		for _, arg := range e.Args {
+			argType, err := arg.typeOf(fields)
+			if err != nil {
+				return Unknown, err
+			}
+
+			if !argType.Is(String) && !argType.Is(Number) {
+				return Unknown, fmt.Errorf("Cannot add values of type %s", argType)
+			}
+
+			if t != "" && argType != t {
+				return Unknown, fmt.Errorf("Cannot add %s to %s", argType, t)
+			}
+			t = argType
+		}
+		return t, nil
+	default:
+		return Unknown, fmt.Errorf("Unknown expression %s", e.Kind)
+	}
+}
+
+// Eval returns the value of `e` for `doc`, or nil if a field
+// it refers to is missing
+func (e Expr) Eval(ctx context.Context, doc Document) (interface{}, error) {
+	switch e.Kind {
+	case LiteralExpr:
+		return e.Value, nil
+	case FuncExpr:
+		fn, ok := Funcs[e.Name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown function %s()", e.Name)
+		}
+		return fn.Call(), nil
+	case VarExpr:
+		if e.Name != "client" {
+			return nil, fmt.Errorf("Unknown variable $%s", e.Name)
+		}
+
+		client, ok := ClientFrom(ctx)
+		if !ok {
+			return nil, nil
+		}
+		return client, nil
+	case FieldExpr:
+		return doc.Fields[e.Name].Value, nil
+	case SumExpr:
+		var sum interface{}
+		for _, arg := range e.Args {
+			v, err := arg.Eval(ctx, doc)
+			if err != nil || v == nil {
+				return nil, err
+			}
+
+			if sum == nil {
+				sum = v
+				continue
+			}
+
+			switch s := sum.(type) {
+			case string:
+				str, ok := v.(string)
+				if !ok {
+					return nil, fmt.Errorf("Cannot add %v to a String", v)
+				}
+				sum = s + str
+			default:
+				total, ok := addNumbers(s, v)
+				if !ok {
+					return nil, fmt.Errorf("Cannot add %v to %v", v, s)
+				}
+				sum = total
+			}
+		}
+		return sum, nil
+	default:
+		return nil, fmt.Errorf("Unknown expression %s", e.Kind)
+	}
+}
+
+// checkComputed infers the type of a computed field whose
+// type is not declared, and reports expressions that are
+// invalid or do not match the type of the field
+func (sf *SchemaField) checkComputed(fields map[string]SchemaField) []error {
+	if sf.Computed == nil {
+		return nil
+	}
+
+	var errs []error
+
+	t, err := sf.Computed.typeOf(fields)
+	if err != nil {
+		return append(errs, err)
+	}
+
+	if sf.Type == "" || sf.Type == Unknown {
+		sf.Type = t
+	} else if t != sf.Type {
+		errs = append(errs, fmt.Errorf("Expression %s has type %s, expected %s", sf.Computed, t, sf.Type))
+	}
+
+	if sf.HasDefault() {
+		errs = append(errs, fmt.Errorf("Computed fields cannot have a default value"))
+	}
+
+	if !sf.Computed.volatile() {
+		return errs
+	}
+
+	// Fields computed on every write are evaluated after the
+	// others, and may not depend on each other
+	for _, name := range sf.Computed.fields() {
+		if other := fields[name]; other.Computed != nil && other.Computed.volatile() {
+			errs = append(errs, fmt.Errorf("Expression %s refers to computed field %s", sf.Computed, name))
+		}
+	}
+
+	return errs
+}
+
+// Compute returns a copy of `doc` with the values of the
+// computed fields of the schema, in place of any values
+// that `doc` holds for them. Fields computed on every write
+// are evaluated after the others, which keep their values in
+// `stored`, the current document when `doc` replaces it, and
+// are evaluated otherwise. A field is removed if its value
+// cannot be computed, e.g. because a field it refers to is
+// missing.
+func (s Schema) Compute(ctx context.Context, doc Document, stored *Document) (Document, error) {
+	var fill, volatile []string
+	for name, field := range s.fields {
+		switch {
+		case field.Computed == nil:
+		case field.Computed.volatile():
+			volatile = append(volatile, name)
+		default:
+			fill = append(fill, name)
+		}
+	}
+
+	if len(fill) == 0 && len(volatile) == 0 {
+		return doc, nil
+	}
+
+	sort.Strings(fill)
+	sort.Strings(volatile)
+
+	out := doc.clone()
+	for _, name := range append(fill, volatile...) {
+		delete(out.Fields, name)
+	}
+
+	for _, name := range append(fill, volatile...) {
+		field := s.fields[name]
+
+		if stored != nil && !field.Computed.volatile() {
+			if f, ok := stored.Fields[name]; ok && f.Value != nil {
+				out.Fields[name] = f
+				continue
+			}
+		}
+
+		v, err := field.Computed.Eval(ctx, out)
+		if err != nil {
+			return doc, fmt.Errorf("Field %s: %w", name, err)
+		}
+
+		if v == nil {
+			delete(out.Fields, name)
+			continue
+		}
+
+		out.Fields[name] = newField(v)
+	}
+
+	return out, nil
+}
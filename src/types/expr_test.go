@@ -0,0 +1,95 @@
+package types
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestComputedFields(t *testing.T) {
+	schema, verr := NewSchemaBuilder().
+		AddField("first", String).
+		AddField("last", String).
+		AddField("id", String, WithComputed(Call("uuid"))).
+		AddField("createdBy", String, WithComputed(Var("client")), Optional).
+		AddField("updatedAt", Timestamp, WithComputed(Call("now"))).
+		AddField("fullName", Unknown, WithComputed(Plus(FieldRef("first"), Lit(" "), FieldRef("last")))).
+		Build()
+	if verr != nil {
+		t.Fatal(verr)
+	}
+
+	if f := schema.fields["fullName"]; f.Type != String {
+		t.Errorf("Want fullName to have type String, Got %s", f.Type)
+	}
+
+	ctx := WithClient(context.Background(), "127.0.0.1:5000")
+	doc, err := schema.Compute(ctx, NewDoc("k").Set(map[string]interface{}{
+		"first":    "Ola",
+		"last":     "Nordmann",
+		"fullName": "ignored",
+		"id":       "ignored",
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := schema.Validate(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := doc.Fields["fullName"].Value; got != "Ola Nordmann" {
+		t.Errorf("Want fullName Ola Nordmann, Got %v", got)
+	}
+
+	if got := doc.Fields["createdBy"].Value; got != "127.0.0.1:5000" {
+		t.Errorf("Want createdBy 127.0.0.1:5000, Got %v", got)
+	}
+
+	id := doc.Fields["id"].Value.(string)
+	if !uuidPattern.MatchString(id) {
+		t.Errorf("Want a UUID, Got %s", id)
+	}
+
+	// Identifiers and authors are kept, whereas derived fields
+	// and timestamps are recomputed. Values sent for them are
+	// ignored.
+	updatedAt := doc.Fields["updatedAt"].Value.(time.Time)
+	updated, err := schema.Compute(context.Background(), doc.Update(map[string]interface{}{
+		"first":     "Kari",
+		"createdBy": "someone else",
+		"id":        "other",
+	}), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := updated.Fields["id"].Value; got != id {
+		t.Errorf("Want id %s, Got %v", id, got)
+	}
+
+	if got := updated.Fields["createdBy"].Value; got != "127.0.0.1:5000" {
+		t.Errorf("Want createdBy to be kept, Got %v", got)
+	}
+
+	if got := updated.Fields["fullName"].Value; got != "Kari Nordmann" {
+		t.Errorf("Want fullName Kari Nordmann, Got %v", got)
+	}
+
+	if got := updated.Fields["updatedAt"].Value.(time.Time); got.Before(updatedAt) {
+		t.Errorf("Want updatedAt after %s, Got %s", updatedAt, got)
+	}
+
+	for name, opt := range map[string]SchemaFieldOption{
+		"unknown function": WithComputed(Call("rand")),
+		"unknown variable": WithComputed(Var("user")),
+		"unknown field":    WithComputed(FieldRef("middle")),
+		"type mismatch":    WithComputed(Call("now")),
+		"mixed sum":        WithComputed(Plus(FieldRef("first"), Lit(1))),
+	} {
+		_, err := NewSchemaBuilder().AddField("first", String).AddField("x", String, opt).Build()
+		if err == nil {
+			t.Errorf("%s: Expected the schema to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// JSONSchemaDialect identifies the version of JSON Schema
+// that schemas are exported to and imported from
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSON Schema has no types for Timestamps and Bytes, which
+// are exported as strings with the date-time format and the
+// base64 content encoding, respectively. Imported strings
+// with either of them are read back as Timestamps and Bytes.
+// References, unique fields and computed fields are
+// exported as the annotations x-keylime-ref,
+// x-keylime-unique and x-keylime-computed. Computed fields
+// are not imported.
+
+// JSONSchema returns the schema as a JSON Schema document
+func (s Schema) JSONSchema() map[string]interface{} {
+	js := s.jsonSchema()
+	js["$schema"] = JSONSchemaDialect
+	return js
+}
+
+func (s Schema) jsonSchema() map[string]interface{} {
+	var (
+		properties = make(map[string]interface{})
+		required   []string
+	)
+
+	for name, field := range s.fields {
+		properties[name] = field.jsonSchema()
+		if field.Required {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(required)
+
+	js := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+
+	if len(required) > 0 {
+		js["required"] = required
+	}
+
+	return js
+}
+
+func (sf SchemaField) jsonSchema() map[string]interface{} {
+	js := make(map[string]interface{})
+
+	switch sf.Type {
+	case String:
+		js["type"] = "string"
+		setIf(js, "minLength", sf.Min)
+		setIf(js, "maxLength", sf.Max)
+		if sf.Pattern != "" {
+			js["pattern"] = sf.Pattern
+		}
+		if sf.Format != "" {
+			js["format"] = sf.Format
+		}
+	case Number:
+		js["type"] = "number"
+		if sf.Integer {
+			js["type"] = "integer"
+		}
+		setIf(js, "minimum", sf.Minimum)
+		setIf(js, "maximum", sf.Maximum)
+	case Boolean:
+		js["type"] = "boolean"
+	case Timestamp:
+		js["type"] = "string"
+		js["format"] = "date-time"
+	case Bytes:
+		js["type"] = "string"
+		js["contentEncoding"] = "base64"
+	case Object:
+		if sf.Schema != nil {
+			js = sf.Schema.jsonSchema()
+		} else {
+			js["type"] = "object"
+		}
+	case Map:
+		js["type"] = "object"
+	case Array:
+		js["type"] = "array"
+		setIf(js, "minItems", sf.Min)
+		setIf(js, "maxItems", sf.Max)
+		if sf.UniqueItems {
+			js["uniqueItems"] = true
+		}
+		if el, ok := sf.elementField(); ok {
+			js["items"] = el.jsonSchema()
+		}
+	}
+
+	if len(sf.Enum) > 0 {
+		js["enum"] = sf.Enum
+	}
+
+	if sf.DefaultValue != nil {
+		js["default"] = sf.DefaultValue
+	}
+
+	if sf.Ref != nil {
+		js["x-keylime-ref"] = sf.Ref
+	}
+
+	if sf.Unique {
+		js["x-keylime-unique"] = true
+	}
+
+	if sf.Computed != nil {
+		js["readOnly"] = true
+		js["x-keylime-computed"] = sf.Computed.String()
+	}
+
+	return js
+}
+
+// setIf sets `key` to the value of `v` unless it is nil
+func setIf(js map[string]interface{}, key string, v interface{}) {
+	switch p := v.(type) {
+	case *int:
+		if p != nil {
+			js[key] = *p
+		}
+	case *json.Number:
+		if p != nil {
+			js[key] = *p
+		}
+	}
+}
+
+// jsonSchemaKeywords are the keywords that may be imported.
+// Other keywords, except for annotations prefixed with x-,
+// are rejected rather than ignored, as the schema would
+// otherwise accept documents that the JSON Schema does not.
+var jsonSchemaKeywords = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true,
+	"description": true, "examples": true, "deprecated": true,
+	"readOnly": true, "writeOnly": true,
+
+	"type": true, "properties": true, "required": true,
+	"additionalProperties": true, "enum": true, "default": true,
+	"minLength": true, "maxLength": true, "pattern": true,
+	"format": true, "contentEncoding": true, "minimum": true,
+	"maximum": true, "items": true, "minItems": true,
+	"maxItems": true, "uniqueItems": true,
+}
+
+// FromJSONSchema builds a schema from a JSON Schema document
+// of type object
+func FromJSONSchema(data []byte) (Schema, error) {
+	var js map[string]interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&js); err != nil {
+		return Schema{}, fmt.Errorf("Invalid JSON Schema: %w", err)
+	}
+
+	if dialect, ok := js["$schema"]; ok && dialect != JSONSchemaDialect {
+		return Schema{}, fmt.Errorf("Unsupported JSON Schema dialect %v, expected %s", dialect, JSONSchemaDialect)
+	}
+
+	s, err := schemaFromJSON(js)
+	if err != nil {
+		return Schema{}, err
+	}
+
+	return *s, nil
+}
+
+func schemaFromJSON(js map[string]interface{}) (*Schema, error) {
+	if typ, _ := jsonType(js); typ != "object" {
+		return nil, fmt.Errorf("Expected a JSON Schema of type object, got %v", js["type"])
+	}
+
+	if err := checkKeywords(js); err != nil {
+		return nil, err
+	}
+
+	if additional, ok := js["additionalProperties"]; ok && additional != false {
+		return nil, fmt.Errorf("additionalProperties must be false")
+	}
+
+	required := make(map[string]bool)
+	names, _ := js["required"].([]interface{})
+	for _, name := range names {
+		s, ok := name.(string)
+		if !ok {
+			return nil, fmt.Errorf("Invalid required field %v", name)
+		}
+		required[s] = true
+	}
+
+	properties, _ := js["properties"].(map[string]interface{})
+
+	sb := NewSchemaBuilder()
+	for name, prop := range properties {
+		p, ok := prop.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: Expected a JSON Schema, got %v", name, prop)
+		}
+
+		t, opts, err := fieldFromJSON(p)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		if !required[name] {
+			opts = append(opts, Optional)
+		}
+
+		sb.AddField(name, t, opts...)
+	}
+
+	s, verr := sb.Build()
+	if verr != nil {
+		return nil, verr
+	}
+
+	return &s, nil
+}
+
+func checkKeywords(js map[string]interface{}) error {
+	for keyword := range js {
+		if !jsonSchemaKeywords[keyword] && !strings.HasPrefix(keyword, "x-") {
+			return fmt.Errorf("Unsupported JSON Schema keyword %s", keyword)
+		}
+	}
+
+	return nil
+}
+
+// jsonType returns the type of a JSON Schema, which may be
+// given along with "null"
+func jsonType(js map[string]interface{}) (string, error) {
+	switch t := js["type"].(type) {
+	case string:
+		return t, nil
+	case []interface{}:
+		var out []string
+		for _, v := range t {
+			if v != "null" {
+				out = append(out, fmt.Sprint(v))
+			}
+		}
+
+		if len(out) != 1 {
+			return "", fmt.Errorf("Expected a single type, got %v", t)
+		}
+		return out[0], nil
+	case nil:
+		if enum, ok := js["enum"].([]interface{}); ok && len(enum) > 0 {
+			switch GetDataType(enum[0]) {
+			case String:
+				return "string", nil
+			case Number:
+				return "number", nil
+			case Boolean:
+				return "boolean", nil
+			}
+		}
+		return "", fmt.Errorf("Missing type")
+	default:
+		return "", fmt.Errorf("Invalid type %v", t)
+	}
+}
+
+func fieldFromJSON(js map[string]interface{}) (Type, []SchemaFieldOption, error) {
+	var (
+		t    Type
+		opts []SchemaFieldOption
+	)
+
+	if err := checkKeywords(js); err != nil {
+		return Unknown, nil, err
+	}
+
+	typ, err := jsonType(js)
+	if err != nil {
+		return Unknown, nil, err
+	}
+
+	if typ == "object" {
+		if _, ok := js["properties"]; !ok {
+			return Map, nil, nil
+		}
+
+		s, err := schemaFromJSON(js)
+		if err != nil {
+			return Unknown, nil, err
+		}
+
+		return Object, []SchemaFieldOption{WithSchema(s)}, nil
+	}
+
+	ints := map[string]func(int) SchemaFieldOption{}
+	numbers := map[string]func(json.Number) SchemaFieldOption{}
+
+	switch typ {
+	case "string":
+		switch {
+		case js["format"] == "date-time":
+			t = Timestamp
+		case js["contentEncoding"] == "base64":
+			t = Bytes
+		default:
+			t = String
+			ints["minLength"] = WithMin
+			ints["maxLength"] = WithMax
+
+			if pattern, ok := js["pattern"].(string); ok {
+				opts = append(opts, WithPattern(pattern))
+			}
+
+			if format, ok := js["format"].(string); ok {
+				opts = append(opts, WithFormat(format))
+			}
+		}
+
+	case "number", "integer":
+		t = Number
+		if typ == "integer" {
+			opts = append(opts, Integer)
+		}
+		numbers["minimum"] = WithMinimum
+		numbers["maximum"] = WithMaximum
+
+	case "boolean":
+		t = Boolean
+
+	case "array":
+		t = Array
+		ints["minItems"] = WithMin
+		ints["maxItems"] = WithMax
+
+		if js["uniqueItems"] == true {
+			opts = append(opts, UniqueItems)
+		}
+
+		if items, ok := js["items"].(map[string]interface{}); ok {
+			elType, elOpts, err := fieldFromJSON(items)
+			if err != nil {
+				return Unknown, nil, fmt.Errorf("items: %w", err)
+			}
+
+			opts = append(opts, WithElements(elType, elOpts...))
+		}
+
+	default:
+		return Unknown, nil, fmt.Errorf("Unsupported type %s", typ)
+	}
+
+	for keyword, opt := range ints {
+		if v, ok := js[keyword]; ok {
+			n, ok := toInt(v)
+			if !ok || n < 0 {
+				return Unknown, nil, fmt.Errorf("%s must be a non-negative integer, got %v", keyword, v)
+			}
+			opts = append(opts, opt(int(n)))
+		}
+	}
+
+	for keyword, opt := range numbers {
+		if v, ok := js[keyword]; ok {
+			r, ok := toRat(v)
+			if !ok {
+				return Unknown, nil, fmt.Errorf("%s must be a number, got %v", keyword, v)
+			}
+			opts = append(opts, opt(ratToNumber(r)))
+		}
+	}
+
+	if enum, ok := js["enum"].([]interface{}); ok {
+		opts = append(opts, WithEnum(enum...))
+	}
+
+	if v, ok := js["default"]; ok {
+		opts = append(opts, WithDefault(v))
+	}
+
+	if ref, ok := js["x-keylime-ref"].(map[string]interface{}); ok {
+		collection, _ := ref["collection"].(string)
+		policy, _ := ref["onDelete"].(string)
+		opts = append(opts, WithRef(collection, RefPolicy(policy)))
+	}
+
+	if js["x-keylime-unique"] == true {
+		opts = append(opts, Unique)
+	}
+
+	return t, opts, nil
+}
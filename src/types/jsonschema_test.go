@@ -0,0 +1,86 @@
+package types
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	address, verr := NewSchemaBuilder().
+		AddField("city", String).
+		AddField("zip", String, WithPattern("^[0-9]{4}$"), Optional).
+		Build()
+	if verr != nil {
+		t.Fatal(verr)
+	}
+
+	schema, verr := NewSchemaBuilder().
+		AddField("name", String, WithMin(1), WithMax(50)).
+		AddField("email", String, WithFormat("email"), Unique).
+		AddField("age", Number, Integer, WithMinimum("0"), Optional).
+		AddField("role", String, WithEnum("admin", "user"), WithDefault("user")).
+		AddField("createdAt", Timestamp, Optional).
+		AddField("avatar", Bytes, Optional).
+		AddField("tags", Array, WithElements(String), UniqueItems, WithMax(10), Optional).
+		AddField("meta", Map, Optional).
+		AddField("address", Object, WithSchema(&address), Optional).
+		AddField("team", String, WithRef("teams", Restrict), Optional).
+		Build()
+	if verr != nil {
+		t.Fatal(verr)
+	}
+
+	data, err := json.Marshal(schema.JSONSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := FromJSONSchema(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.String() != schema.String() {
+		t.Errorf("Want\n%s\nGot\n%s", schema, got)
+	}
+
+	want, _ := json.Marshal(schema.Describe())
+	described, _ := json.Marshal(got.Describe())
+	if string(described) != string(want) {
+		t.Errorf("Want %s, Got %s", want, described)
+	}
+
+	t.Run("Nullable types are optional", func(t *testing.T) {
+		s, err := FromJSONSchema([]byte(`{
+			"type": "object",
+			"properties": {"nickname": {"type": ["string", "null"]}}
+		}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s.fields["nickname"].Type != String || s.fields["nickname"].Required {
+			t.Errorf("Want an optional String, Got %+v", s.fields["nickname"])
+		}
+	})
+
+	for _, test := range []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{"Not an object", `{"type": "string"}`, "type object"},
+		{"Unsupported keyword", `{"type": "object", "properties": {"a": {"oneOf": []}}}`, "oneOf"},
+		{"Open objects", `{"type": "object", "additionalProperties": true}`, "additionalProperties"},
+		{"Other dialects", `{"$schema": "http://json-schema.org/draft-07/schema#", "type": "object"}`, "dialect"},
+		{"Invalid constraint", `{"type": "object", "properties": {"a": {"type": "string", "minLength": -1}}}`, "minLength"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := FromJSONSchema([]byte(test.schema))
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Want error containing %q, Got %v", test.want, err)
+			}
+		})
+	}
+}
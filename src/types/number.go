@@ -0,0 +1,145 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// Numbers decoded from JSON are kept as json.Number, which
+// holds the exact decimal text of the number. Integers
+// larger than 2^53 and decimals such as 0.1 are therefore
+// stored, compared and returned without losing precision.
+// Numbers set from Go may also be any of the built-in
+// integer and floating point types.
+
+// ParseNumber returns the Number represented by `s`, which
+// must be a JSON number such as -12 or 1.5e3
+func ParseNumber(s string) (json.Number, error) {
+	var n json.Number
+	if err := json.Unmarshal([]byte(s), &n); err != nil || n == "" {
+		return "", fmt.Errorf("Invalid Number: %s", s)
+	}
+
+	return n, nil
+}
+
+// toRat converts the value of a Number field to an exact
+// rational number
+func toRat(v interface{}) (*big.Rat, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		return new(big.Rat).SetString(string(n))
+	case int:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int64:
+		return new(big.Rat).SetInt64(n), true
+	case uint:
+		return new(big.Rat).SetUint64(uint64(n)), true
+	case float32, float64:
+		f, _ := toFloat(n)
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, false
+		}
+		// A float stands for the shortest decimal that rounds
+		// to it, e.g. 0.1 rather than 0.1000000000000000055...
+		return new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	default:
+		return nil, false
+	}
+}
+
+// toInt converts the value of a Number field to an int64 if
+// it is an integer that fits
+func toInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		i, err := strconv.ParseInt(string(n), 10, 64)
+		return i, err == nil
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// compareNumbers returns -1, 0 or 1 if a is less than, equal
+// to or greater than b. The comparison is exact.
+func compareNumbers(a, b interface{}) (int, bool) {
+	if x, ok := toInt(a); ok {
+		if y, ok := toInt(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			default:
+				return 0, true
+			}
+		}
+	}
+
+	x, aok := toRat(a)
+	y, bok := toRat(b)
+	if !aok || !bok {
+		return 0, false
+	}
+
+	return x.Cmp(y), true
+}
+
+// isInteger reports whether the value of a Number field is
+// a whole number
+func isInteger(v interface{}) bool {
+	r, ok := toRat(v)
+	return ok && r.IsInt()
+}
+
+// addNumbers returns the exact sum of two Numbers
+func addNumbers(a, b interface{}) (interface{}, bool) {
+	x, aok := toRat(a)
+	y, bok := toRat(b)
+	if !aok || !bok {
+		return nil, false
+	}
+
+	return ratToNumber(x.Add(x, y)), true
+}
+
+// ratToNumber formats `r` as a Number. The result is exact
+// if `r` has a finite decimal expansion, such as a sum of
+// decimals, and otherwise the float64 nearest to `r`.
+func ratToNumber(r *big.Rat) json.Number {
+	if r.IsInt() {
+		return json.Number(r.Num().String())
+	}
+
+	// The expansion is finite if the denominator has no prime
+	// factors other than 2 and 5, and has as many digits as
+	// the larger of their powers
+	var (
+		d      = new(big.Int).Set(r.Denom())
+		m      = new(big.Int)
+		digits = 0
+	)
+	for _, p := range []int64{2, 5} {
+		n := 0
+		for q := big.NewInt(p); m.Mod(d, q).Sign() == 0; n++ {
+			d.Quo(d, q)
+		}
+		if n > digits {
+			digits = n
+		}
+	}
+
+	if d.IsInt64() && d.Int64() == 1 {
+		return json.Number(r.FloatString(digits))
+	}
+
+	f, _ := r.Float64()
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+}
@@ -0,0 +1,107 @@
+package types
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+)
+
+func TestNumberPrecision(t *testing.T) {
+	big := json.Number("9007199254740993") // 2^53 + 1
+
+	for i, test := range []struct {
+		a, b interface{}
+		want int
+	}{
+		{big, json.Number("9007199254740992"), 1},
+		{big, 9007199254740992.0, 1},
+		{json.Number("0.1"), 0.1, 0},
+		{json.Number("0.10"), json.Number("0.1"), 0},
+		{json.Number("-2"), 1, -1},
+		{json.Number("1e3"), 1000, 0},
+	} {
+		got, err := newField(test.a).Compare(newField(test.b))
+		if err != nil {
+			t.Fatalf("%d: Unexpected error: %s", i, err)
+		}
+
+		if got != test.want {
+			t.Errorf("%d: %v <=> %v Want=%d Got=%d", i, test.a, test.b, test.want, got)
+		}
+	}
+
+	doc := NewDoc("k").Set(map[string]interface{}{"id": big, "price": json.Number("19.99")})
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Document
+	if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := decoded.Fields["id"].Value; got != big {
+		t.Errorf("Want %s after gob round trip, Got %v", big, got)
+	}
+
+	inc, err := decoded.Apply(UpdateOp{Kind: IncOp, Path: []string{"id"}, Value: json.Number("1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := inc.Fields["id"].Value; got != json.Number("9007199254740994") {
+		t.Errorf("Want exact sum, Got %v", got)
+	}
+
+	schema, _ := NewSchemaBuilder().
+		AddField("id", Number, Integer).
+		AddField("price", Number, WithMaximum("19.99")).
+		Build()
+
+	if err := schema.Validate(decoded); err != nil {
+		t.Errorf("Want nil error, Got %s", err)
+	}
+
+	over := NewDoc("k").Set(map[string]interface{}{"id": json.Number("1.5"), "price": json.Number("19.991")})
+	if ve, ok := schema.Validate(over).(ValidationError); !ok || len(ve) != 2 {
+		t.Errorf("Expected id and price to be invalid, Got %v", ve)
+	}
+}
+
+func TestExactArithmetic(t *testing.T) {
+	doc := NewDoc("k").Set(map[string]interface{}{"price": json.Number("0.2")})
+
+	inc, err := doc.Apply(UpdateOp{Kind: IncOp, Path: []string{"price"}, Value: json.Number("0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := inc.Fields["price"].Value; got != json.Number("0.3") {
+		t.Errorf("0.2 += 0.1: Want 0.3, Got %v", got)
+	}
+
+	for i, test := range []struct {
+		agg    AggregateFunc
+		values []interface{}
+		want   json.Number
+	}{
+		{Sum, []interface{}{json.Number("0.1"), json.Number("0.2")}, "0.3"},
+		{Sum, []interface{}{json.Number("-1.25"), 0.5, 2}, "1.25"},
+		{Avg, []interface{}{json.Number("0.1"), json.Number("0.2")}, "0.15"},
+		{Avg, []interface{}{json.Number("1"), json.Number("1"), json.Number("2")}, "1.3333333333333333"},
+	} {
+		acc := NewAccumulator(Aggregate{test.agg, []string{"n"}})
+		for _, v := range test.values {
+			if err := acc.Add(NewDoc("k").Set(map[string]interface{}{"n": v})); err != nil {
+				t.Fatalf("%d: Unexpected error: %s", i, err)
+			}
+		}
+
+		if got := acc.Value(); got != test.want {
+			t.Errorf("%d: %s%v Want=%v Got=%v", i, test.agg, test.values, test.want, got)
+		}
+	}
+}
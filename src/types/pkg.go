@@ -3,10 +3,12 @@
 // The basic types include:
 //
 // * Boolean
-// * Number
+// * Number, which keeps the exact value it was written with
 // * String
 // * Object
 // * Array
+// * Timestamp, written in JSON as an RFC 3339 string
+// * Bytes, written in JSON as a base64 string
 //
 // More complex and abstract data types are built on top of
 // these basic types.
@@ -31,29 +33,232 @@ package types
 import (
 	"context"
 	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
 )
 
 func init() {
 	gob.Register(map[string]interface{}{})
 	gob.Register([]interface{}{})
+	gob.Register(time.Time{})
+	gob.Register(json.Number(""))
 }
 
 type Store interface {
 	Collection(name string) (Collection, error)
+	Collections(ctx context.Context) ([]string, error)
+	Drop(ctx context.Context, name string) error
+	Rename(ctx context.Context, from, to string) error
+
+	// OpenImport opens a file in the import directory for LOAD
+	OpenImport(path string) (io.ReadCloser, error)
 }
 
 // A Collection represents a named set of Documents.
 type Collection interface {
 	Get(ctx context.Context, k string) (*Document, error)
-	GetFirst(ctx context.Context, n int) ([]Document, error)
-	GetLast(ctx context.Context, n int) ([]Document, error)
+	GetRevision(ctx context.Context, k string, rev int) (*Document, error)
+	History(ctx context.Context, k string) ([]Document, error)
+	GetFirst(ctx context.Context, n int, opts ListOptions) (*Page, error)
+	GetLast(ctx context.Context, n int, opts ListOptions) (*Page, error)
+	Scan(ctx context.Context, asc bool, opts ListOptions) (Cursor, error)
+	Aggregate(ctx context.Context, q AggregateQuery) ([]map[string]interface{}, error)
+	Count(ctx context.Context, where Filter) (int, error)
+
+	GetMany(ctx context.Context, keys []string) ([]*Document, error)
 
-	Set(ctx context.Context, k string, fields map[string]interface{}) error
+	Set(ctx context.Context, k string, fields map[string]interface{}, opts ...DocumentOption) error
+	Upsert(ctx context.Context, k string, fields map[string]interface{}, opts ...DocumentOption) error
+	Insert(ctx context.Context, fields map[string]interface{}, opts ...DocumentOption) (string, error)
+	SetMany(ctx context.Context, docs map[string]map[string]interface{}, mode BatchMode) error
 	Delete(ctx context.Context, k string) error
+	DeleteIf(ctx context.Context, k string, version string) error
 	Update(ctx context.Context, k string, fields map[string]interface{}) error
-	Create(ctx context.Context, s *Schema) error
+	UpdateIf(ctx context.Context, k string, version string, fields map[string]interface{}) error
+	Apply(ctx context.Context, k string, ops []UpdateOp) error
+	ApplyIf(ctx context.Context, k string, version string, ops []UpdateOp) error
+	Create(ctx context.Context, s *Schema, opts ...CollectionOption) error
+	BulkLoad(ctx context.Context, it DocumentIterator) (int, error)
+	Expand(ctx context.Context, doc Document, fields ...string) (Document, error)
+
+	GetSchema(ctx context.Context) (Schema, error)
+	Info(ctx context.Context) (*CollectionStats, error)
+}
+
+// CollectionConfig holds the settings a collection is
+// created with
+type CollectionConfig struct {
+	// HistorySize is the number of previous revisions kept for
+	// each document. No history is kept if it is 0.
+	HistorySize int
+
+	// DefaultTTL is the time to live of documents written
+	// without an explicit TTL. Documents do not expire by
+	// default if it is 0.
+	DefaultTTL time.Duration
+
+	// Keys is the strategy used to generate the keys of
+	// inserted documents. UUIDKeys is used if it is empty.
+	Keys KeyStrategy
+}
+
+// A KeyStrategy determines how the keys of inserted
+// documents are generated
+type KeyStrategy string
+
+// Key strategies
+const (
+	UUIDKeys     KeyStrategy = "uuid"     // Random UUIDv4
+	ULIDKeys                 = "ulid"     // Time-ordered ULID
+	UUIDv7Keys               = "uuidv7"   // Time-ordered UUIDv7
+	SequenceKeys             = "sequence" // Increasing integers, starting at 1, zero-padded to 20 digits
+)
+
+// KeyStrategies are the valid key strategies
+var KeyStrategies = map[KeyStrategy]bool{
+	UUIDKeys:     true,
+	ULIDKeys:     true,
+	UUIDv7Keys:   true,
+	SequenceKeys: true,
+}
+
+// CollectionOption configures a collection when it is
+// created
+type CollectionOption func(*CollectionConfig)
+
+// WithHistory keeps the last `n` revisions of every
+// document in the collection
+func WithHistory(n int) CollectionOption {
+	return func(cfg *CollectionConfig) {
+		cfg.HistorySize = n
+	}
+}
+
+// WithDefaultTTL makes documents in the collection expire
+// `ttl` after they are written, unless another TTL is given
+func WithDefaultTTL(ttl time.Duration) CollectionOption {
+	return func(cfg *CollectionConfig) {
+		cfg.DefaultTTL = ttl
+	}
+}
+
+// WithKeys generates the keys of documents inserted into the
+// collection with `strategy`
+func WithKeys(strategy KeyStrategy) CollectionOption {
+	return func(cfg *CollectionConfig) {
+		cfg.Keys = strategy
+	}
+}
+
+// BatchMode determines how a batch write handles documents
+// that cannot be written
+type BatchMode int
+
+const (
+	// AllOrNothing rejects the entire batch if any of its
+	// documents is invalid
+	AllOrNothing BatchMode = iota
+
+	// Partial writes every valid document in the batch and
+	// reports the ones that were rejected
+	Partial
+)
+
+// BatchError maps the keys of the documents in a batch that
+// could not be written to the reason they were rejected
+type BatchError map[string]error
+
+func (be BatchError) Error() string {
+	var keys []string
+	for k := range be {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString("Batch rejected documents:\n")
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf("%s: %s\n", k, be[k]))
+	}
+
+	return sb.String()
+}
+
+// MarshalJSON encodes the error as an object that maps the
+// key of each rejected document to the reason it was
+// rejected, and to a list of field errors if it failed
+// validation
+func (be BatchError) MarshalJSON() ([]byte, error) {
+	type rejection struct {
+		Error  string       `json:"error"`
+		Fields []FieldError `json:"fields,omitempty"`
+	}
+
+	out := make(map[string]rejection, len(be))
+	for k, err := range be {
+		r := rejection{Error: err.Error()}
+
+		var ve ValidationError
+		if errors.As(err, &ve) {
+			r.Error = "Validation failed"
+			r.Fields = ve.Fields()
+		}
+
+		out[k] = r
+	}
+
+	return json.Marshal(out)
+}
+
+// A DocumentIterator yields a sequence of documents. Next
+// advances the iterator and reports whether a document is
+// available through Value. Once Next returns false, Err
+// returns the error, if any, that stopped the iteration.
+type DocumentIterator interface {
+	Next() bool
+	Value() Document
+	Err() error
+}
+
+// A Cursor is a DocumentIterator over a listing that can be
+// resumed. Token returns an opaque token identifying the
+// position of the current document; a cursor created with
+// the token yields the documents that follow it.
+type Cursor interface {
+	DocumentIterator
+	Token() string
+}
+
+// A Page is a slice of a listing. Cursor is the token to
+// pass to fetch the next page, and HasMore reports whether
+// there are documents after the page.
+type Page struct {
+	Documents []Document
+	Cursor    string
+	HasMore   bool
+}
+
+// ListOptions control which slice of a listing is returned
+type ListOptions struct {
+	// After is a cursor token. If it is set, the listing
+	// starts after the document it identifies.
+	After string
+
+	// Skip is the number of documents to skip
+	Skip int
+
+	// ByKey lists documents in key order rather than in
+	// insertion order
+	ByKey bool
 
-	Info(ctx context.Context) string
+	// OrderBy lists documents ordered by the values of their
+	// fields. It takes precedence over ByKey.
+	OrderBy OrderBy
 }
 
 type Type string
@@ -64,20 +269,22 @@ func (t Type) Is(other Type) bool {
 
 // KeyLime data types
 const (
-	Boolean Type = "Boolean"
-	Number       = "Number"
-	Object       = "Object" // Object is a Map with a schema
-	Map          = "Map"
-	Array        = "Array"
-	String       = "String"
-	Unknown      = "Unknown"
+	Boolean   Type = "Boolean"
+	Number         = "Number"
+	Object         = "Object" // Object is a Map with a schema
+	Map            = "Map"
+	Array          = "Array"
+	String         = "String"
+	Timestamp      = "Timestamp"
+	Bytes          = "Bytes"
+	Unknown        = "Unknown"
 )
 
 func GetDataType(s interface{}) Type {
 	switch s.(type) {
 	case string:
 		return String
-	case int, float32, float64, uint:
+	case int, int64, float32, float64, uint, json.Number:
 		return Number
 	case map[string]interface{}:
 		return Map
@@ -87,6 +294,10 @@ func GetDataType(s interface{}) Type {
 		return Array
 	case bool:
 		return Boolean
+	case time.Time:
+		return Timestamp
+	case []byte:
+		return Bytes
 	default:
 		return Unknown
 	}
@@ -0,0 +1,172 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StepKind is the kind of a step in a field path
+type StepKind int
+
+// Field path steps
+const (
+	FieldStep    StepKind = iota // The field with a given name
+	IndexStep                    // The array element at a given index
+	WildcardStep                 // Every array element or object field
+)
+
+// A Step selects one or more values from an object or an
+// array
+type Step struct {
+	Kind  StepKind
+	Name  string
+	Index int
+}
+
+// A FieldPath selects values nested inside a document, e.g.
+// address.city, tags[0] or friends[*].name
+type FieldPath []Step
+
+func (p FieldPath) String() string {
+	var sb strings.Builder
+	for i, step := range p {
+		switch step.Kind {
+		case FieldStep:
+			if i > 0 {
+				sb.WriteString(".")
+			}
+			sb.WriteString(step.Name)
+		case IndexStep:
+			fmt.Fprintf(&sb, "[%d]", step.Index)
+		case WildcardStep:
+			sb.WriteString("[*]")
+		}
+	}
+
+	return sb.String()
+}
+
+// Eval returns the value selected by the path in the
+// document, and whether it exists. A wildcard yields an
+// array with the values selected from each element of an
+// array, or an object with the values selected from each
+// field of an object. Elements that do not have the rest of
+// the path are left out.
+func (p FieldPath) Eval(doc Document) (interface{}, bool) {
+	root := make(map[string]interface{}, len(doc.Fields))
+	for name, f := range doc.Fields {
+		root[name] = f.Value
+	}
+
+	return p.eval(root)
+}
+
+func (p FieldPath) eval(v interface{}) (interface{}, bool) {
+	if len(p) == 0 {
+		return v, true
+	}
+
+	step, rest := p[0], p[1:]
+
+	switch step.Kind {
+	case FieldStep:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		child, ok := obj[step.Name]
+		if !ok {
+			return nil, false
+		}
+
+		return rest.eval(child)
+
+	case IndexStep:
+		arr, ok := v.([]interface{})
+		if !ok || step.Index < 0 || step.Index >= len(arr) {
+			return nil, false
+		}
+
+		return rest.eval(arr[step.Index])
+
+	case WildcardStep:
+		switch c := v.(type) {
+		case []interface{}:
+			out := []interface{}{}
+			for _, e := range c {
+				if r, ok := rest.eval(e); ok {
+					out = append(out, r)
+				}
+			}
+
+			return out, true
+
+		case map[string]interface{}:
+			out := make(map[string]interface{})
+			for name, e := range c {
+				if r, ok := rest.eval(e); ok {
+					out[name] = r
+				}
+			}
+
+			return out, true
+		}
+	}
+
+	return nil, false
+}
+
+// A Projection selects the value at Path and names it Name
+// in the result
+type Projection struct {
+	Path FieldPath
+	Name string
+}
+
+// ProjectOptions control how a document is projected
+type ProjectOptions struct {
+	// Strict makes Project fail if a path does not exist in
+	// the document. Otherwise, missing values are null.
+	Strict bool
+
+	// Typed returns values as Fields that carry the type of
+	// the value
+	Typed bool
+}
+
+// MissingPathsError lists the paths of a projection that do
+// not exist in a document
+type MissingPathsError []string
+
+func (e MissingPathsError) Error() string {
+	return fmt.Sprintf("Paths do not exist: %s", strings.Join(e, ", "))
+}
+
+// Project returns the values selected by the projections,
+// keyed by their names
+func (d Document) Project(ps []Projection, opts ProjectOptions) (map[string]interface{}, error) {
+	var (
+		out     = make(map[string]interface{})
+		missing MissingPathsError
+	)
+
+	for _, p := range ps {
+		v, ok := p.Path.Eval(d)
+		switch {
+		case !ok:
+			missing = append(missing, p.Path.String())
+			out[p.Name] = nil
+		case opts.Typed:
+			out[p.Name] = newField(v)
+		default:
+			out[p.Name] = v
+		}
+	}
+
+	if opts.Strict && len(missing) > 0 {
+		return nil, missing
+	}
+
+	return out, nil
+}
@@ -0,0 +1,60 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProject(t *testing.T) {
+	doc := NewDoc("k").Set(map[string]interface{}{
+		"name":    "Ola",
+		"address": map[string]interface{}{"city": "Bergen", "zip": "5003"},
+		"tags":    []interface{}{"a", "b"},
+		"friends": []interface{}{
+			map[string]interface{}{"name": "Kari"},
+			map[string]interface{}{"age": 3.0},
+			map[string]interface{}{"name": "Per"},
+		},
+	})
+
+	field := func(name string) Step { return Step{Kind: FieldStep, Name: name} }
+	index := func(i int) Step { return Step{Kind: IndexStep, Index: i} }
+	wildcard := Step{Kind: WildcardStep}
+
+	ps := []Projection{
+		{FieldPath{field("name")}, "n"},
+		{FieldPath{field("address"), field("city")}, "city"},
+		{FieldPath{field("tags"), index(1)}, "tags[1]"},
+		{FieldPath{field("friends"), wildcard, field("name")}, "friends"},
+		{FieldPath{field("address"), wildcard}, "address"},
+		{FieldPath{field("tags"), index(2)}, "missing"},
+	}
+
+	got, err := doc.Project(ps, ProjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"n":       "Ola",
+		"city":    "Bergen",
+		"tags[1]": "b",
+		"friends": []interface{}{"Kari", "Per"},
+		"address": map[string]interface{}{"city": "Bergen", "zip": "5003"},
+		"missing": nil,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Want %v Got %v", want, got)
+	}
+
+	_, err = doc.Project(ps, ProjectOptions{Strict: true})
+	if paths, ok := err.(MissingPathsError); !ok || !reflect.DeepEqual([]string(paths), []string{"tags[2]"}) {
+		t.Errorf("Want MissingPathsError [tags[2]], Got %v", err)
+	}
+
+	typed, _ := doc.Project(ps[:1], ProjectOptions{Typed: true})
+	if f, ok := typed["n"].(Field); !ok || f.Type != String {
+		t.Errorf("Want typed String field, Got %v", typed["n"])
+	}
+}
@@ -0,0 +1,68 @@
+package types
+
+import "fmt"
+
+// A RefPolicy determines what happens to the documents that
+// refer to a document when it is deleted
+type RefPolicy string
+
+// On-delete policies
+const (
+	NoAction RefPolicy = ""         // Referring documents are left as they are
+	Restrict RefPolicy = "restrict" // The delete is rejected
+	Cascade  RefPolicy = "cascade"  // Referring documents are deleted
+	SetNull  RefPolicy = "set-null" // The referring field is removed
+)
+
+// A Reference is a field that holds the key of a document in
+// Collection
+type Reference struct {
+	Collection string    `json:"collection"`
+	OnDelete   RefPolicy `json:"onDelete,omitempty"`
+}
+
+func (r Reference) String() string {
+	if r.OnDelete == NoAction {
+		return fmt.Sprintf("Ref(%s)", r.Collection)
+	}
+
+	return fmt.Sprintf("Ref(%s, onDelete=%s)", r.Collection, quote(string(r.OnDelete)))
+}
+
+// WithRef makes a String field refer to documents in
+// `collection`, applying `policy` when they are deleted
+func WithRef(collection string, policy RefPolicy) SchemaFieldOption {
+	return func(sf *SchemaField) {
+		sf.Ref = &Reference{Collection: collection, OnDelete: policy}
+	}
+}
+
+// checkReference reports a reference that is invalid or
+// whose policy cannot be applied to the field
+func (sf SchemaField) checkReference() []error {
+	if sf.Ref == nil {
+		return nil
+	}
+
+	var errs []error
+
+	if !sf.Type.Is(String) {
+		errs = append(errs, fmt.Errorf("References only apply to fields of type String"))
+	}
+
+	if sf.Ref.Collection == "" {
+		errs = append(errs, fmt.Errorf("Reference is missing a collection"))
+	}
+
+	switch sf.Ref.OnDelete {
+	case NoAction, Restrict, Cascade:
+	case SetNull:
+		if sf.Required {
+			errs = append(errs, fmt.Errorf("Field must be optional to be removed when the document it refers to is deleted"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("Unknown on-delete policy: %s", sf.Ref.OnDelete))
+	}
+
+	return errs
+}
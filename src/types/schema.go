@@ -3,6 +3,7 @@ package types
 import (
 	"bytes"
 	"encoding/gob"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
@@ -122,6 +123,38 @@ func (s Schema) String() string {
 	return s.StringIndent(0)
 }
 
+// spec returns the type of the field along with its
+// constraints as they are written in a schema
+func (sf SchemaField) spec(n int) string {
+	var sb strings.Builder
+
+	switch {
+	case len(sf.Enum) > 0:
+		sb.WriteString(fmt.Sprintf("Enum(%s)", sf.enumString()))
+	case sf.Ref != nil:
+		sb.WriteString(sf.Ref.String())
+	case sf.Elements != nil:
+		sb.WriteString(fmt.Sprintf("[%s]", sf.Elements.spec(n)))
+	case sf.Type.Is(Array):
+		sb.WriteString("[]")
+		if sf.Schema != nil {
+			sb.WriteString(sf.Schema.StringIndent(n + 1))
+		} else if sf.ElementType != nil {
+			sb.WriteString(string(*sf.ElementType))
+		}
+	case sf.Type.Is(Object) && sf.Schema != nil:
+		sb.WriteString(sf.Schema.StringIndent(n + 1))
+	default:
+		sb.WriteString(string(sf.Type))
+	}
+
+	if constraints := sf.constraintStrings(); len(constraints) > 0 {
+		sb.WriteString(fmt.Sprintf("(%s)", strings.Join(constraints, ", ")))
+	}
+
+	return sb.String()
+}
+
 func (s Schema) StringIndent(n int) string {
 	var sb strings.Builder
 
@@ -143,36 +176,12 @@ func (s Schema) StringIndent(n int) string {
 
 		sb.WriteString(fmt.Sprintf("%s%s: ", prefix, name))
 
-		if !field.Type.Is(Object) && !field.Type.Is(Array) {
-			sb.WriteString(fmt.Sprintf("%s", string(field.Type)))
-		}
-
-		if field.Type.Is(Array) {
-			sb.WriteString("[]")
-		}
-
-		if field.Schema != nil {
-			sb.WriteString(fmt.Sprintf("%s", field.Schema.StringIndent(n+1)))
-		}
-
-
-		if field.ElementType != nil {
-			sb.WriteString(string(*field.ElementType))
-		}
-
-		if field.Min != nil || field.Max != nil {
-			sb.WriteString("(")
-			if field.Min != nil {
-				sb.WriteString(fmt.Sprint(*field.Min))
-			}
+		sb.WriteString(field.spec(n))
 
-			if field.Max != nil {
-				sb.WriteString(fmt.Sprintf(",%d", *field.Max))
-			}
-			sb.WriteString(")")
+		if field.Unique {
+			sb.WriteString(" UNIQUE")
 		}
 
-
 		if !field.Required {
 			sb.WriteString("?")
 		}
@@ -183,6 +192,12 @@ func (s Schema) StringIndent(n int) string {
 			sb.WriteString(fmt.Sprint(field.DefaultValue))
 		}
 
+		if field.Computed != nil {
+			sb.WriteString(" = ")
+
+			sb.WriteString(field.Computed.String())
+		}
+
 		if i < len(s.fields)-1 {
 			sb.WriteString(",\n")
 		}
@@ -227,18 +242,35 @@ func (s *SchemaBuilder) Build() (Schema, ValidationError) {
 	errors := make(ValidationError)
 
 	for name, schemaField := range s.fields {
+		if errs := schemaField.checkComputed(s.fields); len(errs) > 0 {
+			errors[name] = append(errors[name], errs...)
+		}
+
 		if schemaField.HasDefault() {
 			defaultField := newField(schemaField.DefaultValue)
 
+			// Timestamps and Bytes are written as Strings
+			if defaultField.IsType(String) && (schemaField.Type.Is(Timestamp) || schemaField.Type.Is(Bytes)) {
+				if defaultField.ToType(schemaField.Type) == nil {
+					schemaField.DefaultValue = defaultField.Value
+				}
+			}
+
 			if defaultField.IsOneOf(Map, Object) && schemaField.Type.Is(Object) {
 				if err := defaultField.Validate(name, schemaField); err != nil {
 					errors[name] = err
 				}
 			} else if !defaultField.IsType(schemaField.Type) {
 				errors[name] = append(errors[name], fmt.Errorf("Invalid default value for field of type %s: %v", schemaField.Type, defaultField.Type))
+			} else if errs := schemaField.validateConstraints(defaultField.Value); len(errs) > 0 {
+				errors[name] = append(errors[name], errs...)
 			}
 		}
 
+		if errs := schemaField.checkConstraints(); len(errs) > 0 {
+			errors[name] = append(errors[name], errs...)
+		}
+
 		if schemaField.Type.Is(Object) && schemaField.Schema == nil {
 			errors[name] = append(errors[name], fmt.Errorf("Field of type Object must have a schema"))
 		}
@@ -270,6 +302,33 @@ func CopyObj(obj map[string]interface{}) map[string]interface{} {
 	return clone
 }
 
+// References returns the fields of the schema that refer to
+// documents in other collections, keyed by field name
+func (s Schema) References() map[string]Reference {
+	out := make(map[string]Reference)
+	for name, field := range s.fields {
+		if field.Ref != nil {
+			out[name] = *field.Ref
+		}
+	}
+
+	return out
+}
+
+// UniqueFields returns the names of the fields of the schema
+// whose values must be distinct, in order
+func (s Schema) UniqueFields() []string {
+	var out []string
+	for name, field := range s.fields {
+		if field.Unique {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+
+	return out
+}
+
 // Extend returns a `SchemaBuilder` that uses the current
 // schema as basis.
 func (s Schema) Extend() *SchemaBuilder {
@@ -321,9 +380,9 @@ func WithElementType(t Type) SchemaFieldOption {
 // WithRange sets a min and max value for a given field. For
 // fields of type String, it sets constraints on the length
 // of the string. For fields of type Array it constrains the
-// number of elements within the array, and for fields of
-// type Number it sets the minimum and maximum number. It
-// has no effect on fields of type Object.
+// number of elements within the array. It has no effect on
+// fields of other types; see WithMinimum and WithMaximum
+// for the value of a Number.
 func WithRange(min, max int) SchemaFieldOption {
 	return func(sf *SchemaField) {
 		sf.Min = &min
@@ -357,6 +416,32 @@ type SchemaField struct {
 	Min          *int
 	Max          *int
 	ElementType  *Type
+
+	// Constraints on the value of the field, see constraint.go
+	Pattern string        // Regular expression a String must match
+	Format  string        // Named format a String must have
+	Enum    []interface{} // The values the field may take
+	Minimum *json.Number  // Smallest value of a Number
+	Maximum *json.Number  // Largest value of a Number
+	Integer bool          // Whether a Number must be a whole number
+
+	// Elements constrains the elements of an Array field. It
+	// is set by WithElements and takes precedence over
+	// ElementType.
+	Elements    *SchemaField
+	UniqueItems bool // Whether the elements of an Array must be distinct
+
+	// Ref makes a String field hold the key of a document in
+	// another collection
+	Ref *Reference
+
+	// Unique requires the values of the field to be distinct
+	// across the documents of a collection
+	Unique bool
+
+	// Computed is the expression that the value of the field
+	// is computed from when a document is written, see expr.go
+	Computed *Expr
 }
 
 // HasDefault reports whether the schema field has a default
@@ -400,6 +485,8 @@ func (s *Schema) GobDecode(data []byte) error {
 	return dec.Decode(&s.fields)
 }
 
+// A ValidationError maps the names of the invalid fields in a
+// document to the reasons they are invalid
 type ValidationError map[string]FieldValidationError
 
 func (ve ValidationError) Error() string {
@@ -415,6 +502,62 @@ func (ve ValidationError) Error() string {
 	return sb.String()
 }
 
+// A FieldError describes why a field is invalid. Fields
+// nested in objects and arrays are named by their path, e.g.
+// address.city or items[3].price.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Fields returns a list of the field errors, including the
+// errors of nested objects, ordered by field path
+func (ve ValidationError) Fields() []FieldError {
+	return ve.fields("")
+}
+
+func (ve ValidationError) fields(prefix string) []FieldError {
+	var names []string
+	for name := range ve {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := []FieldError{}
+	for _, name := range names {
+		for _, err := range ve[name] {
+			out = append(out, fieldErrors(prefix+name, err)...)
+		}
+	}
+
+	return out
+}
+
+// fieldErrors returns the field errors of the value at
+// `path`, where nested objects extend the path with their
+// field names and arrays with the indices of their elements
+func fieldErrors(path string, err error) []FieldError {
+	switch e := err.(type) {
+	case ValidationError:
+		return e.fields(path + ".")
+	case ElementError:
+		return fieldErrors(fmt.Sprintf("%s[%d]", path, e.Index), e.Err)
+	case FieldValidationError:
+		var out []FieldError
+		for _, err := range e {
+			out = append(out, fieldErrors(path, err)...)
+		}
+		return out
+	default:
+		return []FieldError{{Field: path, Message: err.Error()}}
+	}
+}
+
+// MarshalJSON encodes the error as a list of field errors
+func (ve ValidationError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ve.Fields())
+}
+
 type FieldValidationError []error
 
 func (fve FieldValidationError) Error() string {
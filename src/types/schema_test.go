@@ -1,10 +1,14 @@
 package types
 
 import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestSchemaBuilder(t *testing.T) {
@@ -404,3 +408,234 @@ func TestSchemaString(t *testing.T) {
 func equal(a, b interface{}) bool {
 	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
 }
+
+func TestValidationErrorFields(t *testing.T) {
+	address, _ := NewSchemaBuilder().AddField("city", String).Build()
+	schema, _ := NewSchemaBuilder().
+		AddField("age", Number).
+		AddField("address", Object, WithSchema(&address)).
+		Build()
+
+	doc := NewDoc("k").Set(map[string]interface{}{
+		"age":     "x",
+		"address": map[string]interface{}{"zip": "0001"},
+		"extra":   true,
+	})
+
+	err := schema.Validate(doc)
+	ve, ok := err.(ValidationError)
+	if !ok {
+		t.Fatalf("Expected ValidationError, Got %v", err)
+	}
+
+	want := []FieldError{
+		{Field: "address.city", Message: "Required field city missing"},
+		{Field: "address.zip", Message: "Unknown field: zip"},
+		{Field: "age", Message: "Expected value of type Number but got String"},
+		{Field: "extra", Message: "Unknown field: extra"},
+	}
+
+	if got := ve.Fields(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Want %v, Got %v", want, got)
+	}
+
+	b, err := json.Marshal(BatchError{"k": ve})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]struct {
+		Error  string       `json:"error"`
+		Fields []FieldError `json:"fields"`
+	}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(decoded["k"].Fields, want) {
+		t.Errorf("Want %v, Got %s", want, b)
+	}
+}
+
+func TestSchemaConstraints(t *testing.T) {
+	schema, err := NewSchemaBuilder().
+		AddField("id", String, WithFormat("uuid")).
+		AddField("created", String, WithFormat("date-time")).
+		AddField("code", String, WithRange(2, 4), WithPattern(`^\d+$`)).
+		AddField("size", String, WithEnum("S", "M", "L"), WithDefault("M"), Optional).
+		AddField("score", Number, WithMinimum("0"), WithMaximum("1")).
+		AddField("count", Number, Integer).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+  code: String(2,4, pattern="^\d+$"),
+  count: Number(integer),
+  created: String(format="date-time"),
+  id: String(format="uuid"),
+  score: Number(0..1),
+  size: Enum("S","M","L")? = M
+}`
+	if got := schema.String(); got != want {
+		t.Errorf("Want\n%s\ngot\n%s", want, got)
+	}
+
+	doc := NewDoc("k").Set(map[string]interface{}{
+		"id":      "0b5a8f2e-1c3d-4e5f-8a9b-0c1d2e3f4a5b",
+		"created": "2021-03-04T05:06:07Z",
+		"code":    "123",
+		"score":   "0.5",
+		"count":   "3",
+	})
+	if err := schema.Validate(doc); err != nil {
+		t.Errorf("Want nil error, Got %s", err)
+	}
+
+	doc = NewDoc("k").Set(map[string]interface{}{
+		"id":      "0b5a8f2e",
+		"created": "2021-03-04",
+		"code":    "12a",
+		"size":    "XL",
+		"score":   1.5,
+		"count":   2.5,
+	})
+	ve, ok := schema.Validate(doc).(ValidationError)
+	if !ok || len(ve) != 6 {
+		t.Errorf("Expected every field to be invalid, Got %v", ve)
+	}
+
+	if _, err := NewSchemaBuilder().AddField("size", String, WithEnum("S"), WithDefault("M")).Build(); err == nil {
+		t.Errorf("Expected default value outside the enum to be rejected")
+	}
+}
+
+func TestTimestampAndBytes(t *testing.T) {
+	schema, err := NewSchemaBuilder().
+		AddField("born", Timestamp).
+		AddField("avatar", Bytes).
+		AddField("seen", Timestamp, WithDefault("2021-01-01T00:00:00Z"), Optional).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := NewDoc("k").Set(map[string]interface{}{
+		"born":   "1990-01-02T03:04:05.5+01:00",
+		"avatar": "aGk=",
+	})
+	if err := schema.Validate(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	born := time.Date(1990, 1, 2, 2, 4, 5, 5e8, time.UTC)
+	if f := doc.Fields["born"]; !f.IsType(Timestamp) || !f.Value.(time.Time).Equal(born) {
+		t.Errorf("Want Timestamp %s, Got %v", born, f)
+	}
+
+	if f := doc.Fields["avatar"]; !f.IsType(Bytes) || string(f.Value.([]byte)) != "hi" {
+		t.Errorf("Want Bytes hi, Got %v", f)
+	}
+
+	if f := schema.WithDefaults(doc).Fields["seen"]; !f.IsType(Timestamp) {
+		t.Errorf("Want default of type Timestamp, Got %v", f)
+	}
+
+	// Documents are stored with gob
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Document
+	if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, f := range doc.Fields {
+		if !f.Equal(decoded.Fields[name]) {
+			t.Errorf("%s: Want %v, Got %v", name, f, decoded.Fields[name])
+		}
+	}
+
+	b, _ := json.Marshal(decoded.Fields["avatar"].Value)
+	if string(b) != `"aGk="` {
+		t.Errorf("Want Bytes to be encoded as base64, Got %s", b)
+	}
+
+	for _, fields := range []map[string]interface{}{
+		{"born": "1990-01-02", "avatar": "aGk="},
+		{"born": "1990-01-02T03:04:05Z", "avatar": "not base64"},
+		{"born": 1990, "avatar": "aGk="},
+	} {
+		if err := schema.Validate(NewDoc("k").Set(fields)); err == nil {
+			t.Errorf("%v: Want error, Got nil", fields)
+		}
+	}
+}
+
+func TestArrayValidation(t *testing.T) {
+	item, _ := NewSchemaBuilder().AddField("price", Number, WithMinimum("0")).Build()
+	schema, err := NewSchemaBuilder().
+		AddField("items", Array, WithElementType(Object), WithSchema(&item), WithRange(1, 3)).
+		AddField("tags", Array, WithElementType(String), UniqueItems, Optional).
+		AddField("dates", Array, WithElementType(Timestamp), Optional).
+		AddField("matrix", Array, WithElements(Array, WithElements(Number, Integer), WithRange(2, 2)), Optional).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+  dates: []Timestamp?,
+  items: []  {
+    price: Number(0..)
+  }(1,3),
+  matrix: [[Number(integer)](2,2)]?,
+  tags: []String(unique)?
+}`
+	if got := schema.String(); got != want {
+		t.Errorf("Want\n%s\ngot\n%s", want, got)
+	}
+
+	doc := NewDoc("k").Set(map[string]interface{}{
+		"items":  []interface{}{map[string]interface{}{"price": 1}},
+		"tags":   []interface{}{"a", "b"},
+		"dates":  []interface{}{"2021-01-01T00:00:00Z"},
+		"matrix": []interface{}{[]interface{}{1, 2}, []interface{}{3, 4}},
+	})
+	if err := schema.Validate(doc); err != nil {
+		t.Fatalf("Want nil error, Got %s", err)
+	}
+
+	if dates := doc.Fields["dates"].Value.([]interface{}); GetDataType(dates[0]) != Timestamp {
+		t.Errorf("Expected elements to be converted to Timestamps, Got %v", dates)
+	}
+
+	doc = NewDoc("k").Set(map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"price": 1},
+			map[string]interface{}{"price": -1},
+			"item",
+			map[string]interface{}{"price": 1},
+		},
+		"tags":   []interface{}{"a", "b", "a"},
+		"matrix": []interface{}{[]interface{}{1, 2.5}, []interface{}{3}},
+	})
+
+	ve, ok := schema.Validate(doc).(ValidationError)
+	if !ok {
+		t.Fatalf("Expected ValidationError, Got %v", ve)
+	}
+
+	var got []string
+	for _, fe := range ve.Fields() {
+		got = append(got, fe.Field)
+	}
+
+	wantPaths := []string{"items[1].price", "items[2]", "items", "matrix[0][1]", "matrix[1]", "tags[2]"}
+	if !reflect.DeepEqual(got, wantPaths) {
+		t.Errorf("Want %v, Got %v", wantPaths, got)
+	}
+}
@@ -0,0 +1,94 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A SortKey orders documents by the value of the field at
+// Path, in descending order if Desc is set
+type SortKey struct {
+	Path []string
+	Desc bool
+}
+
+func (sk SortKey) String() string {
+	dir := "ASC"
+	if sk.Desc {
+		dir = "DESC"
+	}
+
+	return fmt.Sprintf("%s %s", strings.Join(sk.Path, "."), dir)
+}
+
+// An OrderBy orders documents by one or more sort keys.
+// Later keys break ties between documents that are equal
+// by earlier ones, and remaining ties are broken by the
+// documents' keys.
+type OrderBy []SortKey
+
+// Compare returns a negative number if `a` is ordered
+// before `b`, a positive number if it is ordered after and
+// 0 if the documents have the same key
+func (o OrderBy) Compare(a, b Document) int {
+	return a.compare(o.by, b)
+}
+
+// Reverse returns the opposite ordering
+func (o OrderBy) Reverse() OrderBy {
+	out := make(OrderBy, len(o))
+	for i, sk := range o {
+		out[i] = SortKey{Path: sk.Path, Desc: !sk.Desc}
+	}
+
+	return out
+}
+
+func (o OrderBy) by(a, b Document) int {
+	for _, sk := range o {
+		cmp := compareAt(sk.Path, a, b)
+		if sk.Desc {
+			cmp = -cmp
+		}
+
+		if cmp != 0 {
+			return cmp
+		}
+	}
+
+	cmp := strings.Compare(a.Key, b.Key)
+	if len(o) > 0 && o[len(o)-1].Desc {
+		cmp = -cmp
+	}
+
+	return cmp
+}
+
+// compareAt compares the fields at `path` in `a` and `b`.
+// A missing field compares greater than any value, and
+// fields of different types are ordered by the name of
+// their type.
+func compareAt(path []string, a, b Document) int {
+	fa, aok := a.Get(path...)
+	fb, bok := b.Get(path...)
+
+	switch {
+	case !aok && !bok:
+		return 0
+	case !aok:
+		return 1
+	case !bok:
+		return -1
+	}
+
+	if fa.Type != fb.Type {
+		return strings.Compare(string(fa.Type), string(fb.Type))
+	}
+
+	cmp, err := fa.Compare(fb)
+	if err != nil {
+		return 0
+	}
+
+	return cmp
+}
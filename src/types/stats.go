@@ -0,0 +1,131 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IndexStats describes the B-tree of an index
+type IndexStats struct {
+	T           int     `json:"t"`       // Minimum degree of the tree
+	Height      int     `json:"height"`  // Number of levels below the root
+	Records     int     `json:"records"` // Number of records in the tree
+	Nodes       int     `json:"nodes"`
+	AverageFill float64 `json:"averageFill"` // Records per node over the capacity of a node
+	Bytes       int64   `json:"bytes"`       // Size of the nodes on disk
+}
+
+func (s IndexStats) String() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "Height: %d\n", s.Height)
+	fmt.Fprintf(&sb, "T: %d\n", s.T)
+	fmt.Fprintf(&sb, "Records: %d\n", s.Records)
+	fmt.Fprintf(&sb, "Nodes: %d\n", s.Nodes)
+	fmt.Fprintf(&sb, "Average fill: %.2f\n", s.AverageFill)
+	fmt.Fprintf(&sb, "Bytes: %d\n", s.Bytes)
+
+	return sb.String()
+}
+
+// BlocklistStats describes the blocks that documents are
+// stored in
+type BlocklistStats struct {
+	BlockSize  int     `json:"blockSize"` // Number of documents a block holds
+	Blocks     int     `json:"blocks"`
+	Docs       int     `json:"docs"`       // Number of live documents
+	Deleted    int     `json:"deleted"`    // Number of deleted documents still held by blocks
+	FillFactor float64 `json:"fillFactor"` // Documents held by blocks over their capacity
+	Bytes      int64   `json:"bytes"`      // Size of the blocks on disk
+}
+
+func (s BlocklistStats) String() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "Block size: %d\n", s.BlockSize)
+	fmt.Fprintf(&sb, "Blocks: %d\n", s.Blocks)
+	fmt.Fprintf(&sb, "Docs: %d\n", s.Docs)
+	fmt.Fprintf(&sb, "Deleted: %d\n", s.Deleted)
+	fmt.Fprintf(&sb, "Fill factor: %.2f\n", s.FillFactor)
+	fmt.Fprintf(&sb, "Bytes: %d\n", s.Bytes)
+
+	return sb.String()
+}
+
+// CollectionStats describes a collection, as returned by
+// INFO
+type CollectionStats struct {
+	Name      string             `json:"name"`
+	CreatedAt time.Time          `json:"createdAt"` // Zero for collections created before it was recorded
+	Config    CollectionConfig   `json:"config"`
+	Schema    []FieldDescription `json:"schema"`
+
+	Docs    int `json:"docs"`
+	Deleted int `json:"deleted"`
+
+	Index  IndexStats            `json:"index"`
+	Unique map[string]IndexStats `json:"unique,omitempty"` // Indexes of unique fields by field name
+	Blocks BlocklistStats        `json:"blocks"`
+
+	// Bytes is the size on disk of the collection's scope,
+	// "total", and of the header, index, unique indexes,
+	// blocks, tombstones and history within it
+	Bytes map[string]int64 `json:"bytes"`
+}
+
+func (s CollectionStats) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("\n---------------\n")
+	fmt.Fprintf(&sb, "Collection: %s\n", s.Name)
+	sb.WriteString("---------------\n")
+
+	if !s.CreatedAt.IsZero() {
+		fmt.Fprintf(&sb, "Created: %s\n", s.CreatedAt.Format(time.RFC3339))
+	}
+	fmt.Fprintf(&sb, "Docs: %d\n", s.Docs)
+	fmt.Fprintf(&sb, "Deleted: %d\n", s.Deleted)
+
+	if len(s.Schema) > 0 {
+		sb.WriteString("\n<Schema>\n")
+		for _, field := range s.Schema {
+			fmt.Fprintf(&sb, "%s: %s", field.Name, field.Type)
+			if !field.Required {
+				sb.WriteString("?")
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	sb.WriteString("\n<Index>\n")
+	sb.WriteString(s.Index.String())
+
+	var unique []string
+	for name := range s.Unique {
+		unique = append(unique, name)
+	}
+	sort.Strings(unique)
+
+	for _, name := range unique {
+		fmt.Fprintf(&sb, "\n<Unique index: %s>\n", name)
+		sb.WriteString(s.Unique[name].String())
+	}
+
+	sb.WriteString("\n<Block list>\n")
+	sb.WriteString(s.Blocks.String())
+
+	var scopes []string
+	for scope := range s.Bytes {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+
+	sb.WriteString("\n<Disk usage>\n")
+	for _, scope := range scopes {
+		fmt.Fprintf(&sb, "%s: %d bytes\n", scope, s.Bytes[scope])
+	}
+
+	return sb.String()
+}
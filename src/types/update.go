@@ -0,0 +1,141 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UpdateKind is the kind of an update operator
+type UpdateKind string
+
+// Update operators
+const (
+	SetOp   UpdateKind = "SET"   // Set the field, creating parent objects as needed
+	IncOp              = "INC"   // Add a number to a Number field
+	UnsetOp            = "UNSET" // Remove the field
+	PushOp             = "PUSH"  // Append a value to an Array field
+)
+
+// An UpdateOp modifies the field at Path. Path is a
+// sequence of field names, where every name but the last
+// refers to an object.
+type UpdateOp struct {
+	Kind  UpdateKind
+	Path  []string
+	Value interface{}
+}
+
+func (op UpdateOp) String() string {
+	return fmt.Sprintf("%s %s %v", op.Kind, strings.Join(op.Path, "."), op.Value)
+}
+
+// Apply returns a copy of the document with the operators
+// applied in order. The document is left unchanged if any
+// of the operators cannot be applied.
+func (d Document) Apply(ops ...UpdateOp) (Document, error) {
+	root := make(map[string]interface{}, len(d.Fields))
+	for name, f := range d.Fields {
+		root[name] = f.Value
+	}
+
+	touched := make(map[string]bool)
+	for _, op := range ops {
+		if len(op.Path) == 0 {
+			return d, fmt.Errorf("%s: Missing field path", op.Kind)
+		}
+
+		if err := apply(root, op.Path, op); err != nil {
+			return d, fmt.Errorf("%s: %w", op, err)
+		}
+
+		touched[op.Path[0]] = true
+	}
+
+	c := d.clone()
+	for name := range touched {
+		if v, ok := root[name]; ok {
+			c.Fields[name] = newField(v)
+		} else {
+			delete(c.Fields, name)
+		}
+	}
+
+	c.LastModified = time.Now()
+
+	return c, nil
+}
+
+// apply applies `op` to the value at `path` in `obj`.
+// Objects along the path are copied before they are
+// modified such that the original document is not changed.
+func apply(obj map[string]interface{}, path []string, op UpdateOp) error {
+	name := path[0]
+
+	if len(path) > 1 {
+		child, ok := obj[name]
+		if !ok {
+			if op.Kind == UnsetOp {
+				return nil
+			}
+			child = map[string]interface{}{}
+		}
+
+		m, ok := child.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("Field %s is not an object", name)
+		}
+
+		m = CopyObj(m)
+		obj[name] = m
+
+		return apply(m, path[1:], op)
+	}
+
+	switch op.Kind {
+	case SetOp:
+		obj[name] = op.Value
+
+	case UnsetOp:
+		delete(obj, name)
+
+	case IncOp:
+		if GetDataType(op.Value) != Number {
+			return fmt.Errorf("Expected a Number to add, got %v", op.Value)
+		}
+
+		cur, exists := obj[name]
+		if !exists {
+			obj[name] = op.Value
+			return nil
+		}
+
+		sum, ok := addNumbers(cur, op.Value)
+		if !ok {
+			return fmt.Errorf("Field %s is not a Number", name)
+		}
+
+		obj[name] = sum
+
+	case PushOp:
+		cur, exists := obj[name]
+		if !exists {
+			obj[name] = []interface{}{op.Value}
+			return nil
+		}
+
+		arr, ok := cur.([]interface{})
+		if !ok {
+			return fmt.Errorf("Field %s is not an Array", name)
+		}
+
+		out := make([]interface{}, len(arr), len(arr)+1)
+		copy(out, arr)
+		obj[name] = append(out, op.Value)
+
+	default:
+		return fmt.Errorf("Unknown update operator %s", op.Kind)
+	}
+
+	return nil
+}
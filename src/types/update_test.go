@@ -0,0 +1,65 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	doc := NewDoc("k").Set(map[string]interface{}{
+		"age":      3.0,
+		"nickname": "x",
+		"tags":     []interface{}{"a"},
+		"address":  map[string]interface{}{"city": "Oslo", "zip": "0150"},
+	})
+
+	got, err := doc.Apply(
+		UpdateOp{IncOp, []string{"age"}, 1.0},
+		UpdateOp{SetOp, []string{"address", "city"}, "Bergen"},
+		UpdateOp{SetOp, []string{"meta", "source"}, "import"},
+		UpdateOp{UnsetOp, []string{"nickname"}, nil},
+		UpdateOp{PushOp, []string{"tags"}, "b"},
+		UpdateOp{PushOp, []string{"roles"}, "admin"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"age":     json.Number("4"),
+		"tags":    []interface{}{"a", "b"},
+		"roles":   []interface{}{"admin"},
+		"address": map[string]interface{}{"city": "Bergen", "zip": "0150"},
+		"meta":    map[string]interface{}{"source": "import"},
+	}
+
+	if len(got.Fields) != len(want) {
+		t.Errorf("Want %d fields, Got %v", len(want), got.Fields)
+	}
+
+	for name, v := range want {
+		if f := got.Fields[name]; !reflect.DeepEqual(f.Value, v) {
+			t.Errorf("%s: Want %v Got %v", name, v, f.Value)
+		}
+	}
+
+	// The original document is not modified
+	if city := doc.Fields["address"].Value.(map[string]interface{})["city"]; city != "Oslo" {
+		t.Errorf("Expected original document to be unchanged, Got city=%v", city)
+	}
+
+	if len(doc.Fields["tags"].Value.([]interface{})) != 1 {
+		t.Errorf("Expected original array to be unchanged")
+	}
+
+	for i, op := range []UpdateOp{
+		{IncOp, []string{"nickname"}, 1.0},
+		{PushOp, []string{"age"}, 1.0},
+		{SetOp, []string{"age", "x"}, 1.0},
+	} {
+		if _, err := doc.Apply(op); err == nil {
+			t.Errorf("%d: Expected %s to fail", i, op)
+		}
+	}
+}